@@ -0,0 +1,40 @@
+package color
+
+import (
+	"github.com/nhooyr/terminfo"
+	"github.com/nhooyr/terminfo/caps"
+)
+
+// builtin is a compiled-in ECMA-48 profile equivalent to xterm-256color.
+var builtin = &terminfo.Terminfo{
+	Names: []string{"ansi-builtin", "ECMA-48 terminal with 256 colors"},
+	Numbers: [caps.NumberCount]int16{
+		caps.MaxColors: 256,
+	},
+	Strings: [caps.StringCount]string{
+		caps.ExitAttributeMode:  "\x1b(B\x1b[m",
+		caps.EnterBoldMode:      "\x1b[1m",
+		caps.EnterUnderlineMode: "\x1b[4m",
+		caps.EnterReverseMode:   "\x1b[7m",
+		caps.EnterBlinkMode:     "\x1b[5m",
+		caps.EnterDimMode:       "\x1b[2m",
+		caps.EnterItalicsMode:   "\x1b[3m",
+		caps.EnterSecureMode:    "\x1b[8m",
+		caps.EnterStandoutMode:  "\x1b[7m",
+		caps.SetAForeground:     "\x1b[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m",
+		caps.SetABackground:     "\x1b[%?%p1%{8}%<%t4%p1%d%e%p1%{16}%<%t10%p1%{8}%-%d%e48;5;%p1%d%;m",
+	},
+	ExtBools:   map[string]bool{},
+	ExtNumbers: map[string]int16{},
+	ExtStrings: map[string]string{},
+}
+
+var builtinTerminal = newTerminal(builtin, false)
+
+// BuiltinTerminal returns a compiled-in terminal that uses the ECMA-48 control
+// sequences of xterm-256color. It needs no terminfo database, so it works in minimal
+// containers. It is used as the default terminal if the terminfo entry of TERM cannot
+// be loaded.
+func BuiltinTerminal() *Terminal {
+	return builtinTerminal
+}
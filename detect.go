@@ -0,0 +1,89 @@
+package color
+
+import (
+	"os"
+	"strconv"
+)
+
+// Level is a level of color support.
+type Level int
+
+const (
+	None      Level = iota // no colors or other attributes
+	Basic16                // the 16 named colors
+	Ansi256                // the 256 color palette
+	TrueColor              // 24-bit colors
+)
+
+// Detect returns the level of color support for output written to f.
+// Color is only enabled by default if f is a terminal, and then the level is
+// determined by the terminal's terminfo entry and the COLORTERM environment variable.
+// It also respects the following environment variables, in order of precedence:
+//
+//	FORCE_COLOR	if set, forces the level to 0 (None), 1 (Basic16), 2 (Ansi256) or 3 (TrueColor),
+//			which also makes DefaultTerminal emit 24-bit colors;
+//			true, an empty value or any other value forces at least Basic16 and false disables color
+//	NO_COLOR	if not empty, disables color
+//	CLICOLOR_FORCE	if not empty or 0, forces at least Basic16
+//	TERM		if dumb, disables color
+//	CLICOLOR	if 0, disables color
+//
+// See https://no-color.org, https://force-color.org and https://bixense.com/clicolors.
+func Detect(f *os.File) Level {
+	return detect(IsTerminal(f), os.LookupEnv)
+}
+
+// detect implements Detect given whether the output is a terminal and a function to look up
+// environment variables.
+func detect(terminal bool, lookupEnv func(string) (string, bool)) Level {
+	getenv := func(key string) string {
+		v, _ := lookupEnv(key)
+		return v
+	}
+	if v, ok := lookupEnv("FORCE_COLOR"); ok {
+		switch v {
+		case "0", "false":
+			return None
+		case "1":
+			return Basic16
+		case "2":
+			return Ansi256
+		case "3":
+			return TrueColor
+		}
+		return atLeastBasic16(TermLevel())
+	}
+	if getenv("NO_COLOR") != "" {
+		return None
+	}
+	if v := getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return atLeastBasic16(TermLevel())
+	}
+	if !terminal || getenv("TERM") == "dumb" || getenv("CLICOLOR") == "0" {
+		return None
+	}
+	return TermLevel()
+}
+
+// TermLevel returns the level of color support of the terminal described by the
+// TERM and COLORTERM environment variables, regardless of where output is written.
+// It is a thin wrapper around the Level method of the terminal returned by DefaultTerminal.
+func TermLevel() Level {
+	return defaultTerminal.Level()
+}
+
+func atLeastBasic16(l Level) Level {
+	if l < Basic16 {
+		return Basic16
+	}
+	return l
+}
+
+var levelNames = [...]string{"None", "Basic16", "Ansi256", "TrueColor"}
+
+func (l Level) String() string {
+	if l >= 0 && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return "Level(" + strconv.Itoa(int(l)) + ")"
+}
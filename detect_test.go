@@ -0,0 +1,42 @@
+package color
+
+import "testing"
+
+var detectCases = []struct {
+	terminal bool
+	env      map[string]string
+	exp      Level
+}{
+	{true, nil, TermLevel()},
+	{false, nil, None},
+	{true, map[string]string{"NO_COLOR": "1"}, None},
+	{true, map[string]string{"NO_COLOR": ""}, TermLevel()},
+	{false, map[string]string{"FORCE_COLOR": "0"}, None},
+	{false, map[string]string{"FORCE_COLOR": "1"}, Basic16},
+	{false, map[string]string{"FORCE_COLOR": "2"}, Ansi256},
+	{false, map[string]string{"FORCE_COLOR": "3"}, TrueColor},
+	{false, map[string]string{"FORCE_COLOR": ""}, atLeastBasic16(TermLevel())},
+	{false, map[string]string{"FORCE_COLOR": "true"}, atLeastBasic16(TermLevel())},
+	{true, map[string]string{"FORCE_COLOR": "false"}, None},
+	{true, map[string]string{"FORCE_COLOR": "2", "NO_COLOR": "1"}, Ansi256},
+	{false, map[string]string{"CLICOLOR_FORCE": "1"}, atLeastBasic16(TermLevel())},
+	{false, map[string]string{"CLICOLOR_FORCE": "0"}, None},
+	{true, map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": "1"}, None},
+	{true, map[string]string{"CLICOLOR_FORCE": "1", "TERM": "dumb"}, atLeastBasic16(TermLevel())},
+	{true, map[string]string{"TERM": "dumb"}, None},
+	{true, map[string]string{"CLICOLOR": "0"}, None},
+	{true, map[string]string{"CLICOLOR": "1"}, TermLevel()},
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	for _, c := range detectCases {
+		lookupEnv := func(key string) (string, bool) {
+			v, ok := c.env[key]
+			return v, ok
+		}
+		if r := detect(c.terminal, lookupEnv); r != c.exp {
+			t.Errorf("Expected %v from %v with terminal %v but result was %v", c.exp, c.env, c.terminal, r)
+		}
+	}
+}
@@ -8,13 +8,126 @@ Verbs:
 	%h[attr...]	replaced with a SGR code that sets all of the attributes in []
 			multiple attributes are + separated
 	%r		an abbreviation for %h[reset]
+	%h[/]		closes the scope opened by the last %h[attr...]
+	%l[url]		opens a hyperlink to url
+	%L		closes the hyperlink opened by the last %l[url]
+
+Hyperlinks:
+
+Text between %l[url] and %L is made into a clickable hyperlink with the OSC 8
+escape sequence. When the verbs are stripped only the text is left, unless the
+hyperlink was opened with %#l[url] in which case " (url)" is appended to the text.
+
+	// "issue 12" linking to https://example.com/12, or "issue 12 (https://example.com/12)"
+	// if the verbs are stripped.
+	Printf("%#l[https://example.com/12]issue 12%L\n")
+
+Scopes:
+
+Each %h[attr...] opens a scope that is closed by %h[/]. Closing a scope resets all
+attributes and then restores those of the enclosing scopes, so scopes nest.
+
+	// Bold "a", bold and red "b" and then bold "c".
+	Printf("%h[bold]a%h[fgRed]b%h[/]c%h[/]")
+
+If a string closes scopes, the attributes of the open scopes are also restored after
+each of its fmt verbs. Thus a Format argument that sets or resets attributes does not
+leak them into the rest of the string.
+
+	inner := Prepare("%h[fgGreen]rip%h[/]")
+	// Bold "panic: ", green "rip" and then bold "!".
+	Printf("%h[bold]panic: %s!%h[/]\n", inner)
+
+%r and %h[reset] close all scopes.
+
+Detecting Color Support:
+
+The package level Print functions only enable color if Detect reports that standard
+output supports it. Detect respects the NO_COLOR, FORCE_COLOR, CLICOLOR and
+CLICOLOR_FORCE conventions as well as TERM=dumb, so output behaves correctly in CI logs
+and when piped to less -R. Use it to create your own Printers too.
+
+	p := color.NewLevel(os.Stderr, color.Detect(os.Stderr))
+
+Color Levels:
+
+Color support is modeled as a Level: None, Basic16, Ansi256 or TrueColor. Printers,
+Formats and RunLevel downsample colors to the closest ones supported by both their level
+and the terminal, so a Printer can be told that its output only supports 16 colors even if
+the terminal supports more. The functions that take a color bool use the level reported by
+TermLevel when color is true.
+
+Terminals:
+
+The control sequences are looked up in the terminfo entry of the terminal named by the
+TERM environment variable. To produce output for a different terminal, for example one
+per client connected to a server, load its Terminal with LoadTerminal or LoadTerminalFile
+and use it to create a Printer. Formats prepared for one terminal are rendered again on
+demand when printed to another.
+
+If the terminfo entry of TERM cannot be loaded, for example in minimal container images
+without a terminfo database, a compiled-in terminal equivalent to xterm-256color is used
+instead so that forced color output still works. It can also be selected explicitly with
+BuiltinTerminal.
+
+	t, err := color.LoadTerminal("linux")
+	if err != nil {
+		// handle error
+	}
+	p := color.NewTerminal(conn, t, t.Level())
 
 Preparing Strings:
 
-While this package is heavily optimized, processing the highlighting verbs is still very expensive. Thus, it makes more sense to process the verbs once and then store the results into a Format structure. The format structure, holds two strings, one for when colored output is enabled and the other for when it is disabled.
+While this package is heavily optimized, processing the highlighting verbs is still very expensive. Thus, it makes more sense to process the verbs once and then store the results into a Format structure. The format structure compiles the format string once into segments of text, attributes and fmt verbs, and then caches the string rendered from them for each level of color support, including None for when colored output is disabled.
 
 Use the Prepare function to create Format structures. Then, use the Printfp like functions to use them as the base format strings, or send them as part of the variadic arguments to any Print function and they will be expanded to their appropriate strings. See Prepare below for an example.
 
+Formats also implement fmt.Formatter and fmt.Stringer, so they can be printed anywhere,
+e.g. as fields of a struct or with the fmt package directly. There they use the level set
+with SetStringLevel, None by default, and widths and precisions are measured in columns
+so that the control sequences do not break alignment.
+
+Printers pad their arguments the same way. Widths like %-20s count the columns a string
+occupies in the terminal rather than its runes: control sequences occupy none, while
+East Asian wide characters and emoji occupy two. Thus tables line up with and without color.
+
+	color.SetStringLevel(color.Detect(os.Stdout))
+	fmt.Printf("%-10s|\n", color.Prepare("%h[fgRed]error%r"))
+
+Styles:
+
+Instead of repeating the same attributes everywhere, they can be registered under a
+name with DefineStyle and then used in highlight verbs as %h[@name]. If the name is
+not also the name of a mode or color, the @ can be omitted. Redefining a style
+changes it everywhere it is used, including in Formats that were already prepared.
+
+	color.DefineStyle("error", "fgRed+bold")
+	color.Printf("%h[@error]error:%r %s\n", "foo")
+
+Styles can also be loaded from a theme file with LoadTheme, which lets users ship their
+own color schemes. See LoadTheme for the format.
+
+Typed Styles:
+
+Attributes can also be built programmatically with the Style type instead of written
+as highlight verbs. Its methods return Formats, so the output respects the color
+setting of whichever Printer prints them.
+
+	warn := color.Style{FG: color.Yellow, BG: color.RGB(1, 2, 3), Bold: true}
+	color.Println(warn.Wrap("warning:"), "foo")
+
+Measuring and Cutting Strings:
+
+Highlighted strings can be measured and cut without breaking their control sequences.
+VisibleWidth returns the columns a string occupies, Truncate cuts it to a width with a
+tail like "…", Wrap breaks it into lines and PadRight, PadLeft and PadCenter pad it.
+Truncate and Wrap reset the attributes active where they cut or break a string, and
+Wrap sets them again at the start of the next line. ActiveAttributes reports
+whether a string leaves attributes set or a hyperlink open at its end.
+
+	s := color.Highlight("%h[fgRed]error:%r %h[bold]connection refused%r")
+	fmt.Println(color.Truncate(s, 11, "…")) // red "error:" then bold "con…"
+
 Errors:
 
 If an error occurs, the generated string will contain a description of the problem, as in these examples.
@@ -23,11 +136,13 @@ If an error occurs, the generated string will contain a description of the probl
 		Printf("%h(fgRed)%s", "hi"):		%!h(INVALID)
 	No attributes in the highlight verb:
 		Printf("%h[]%s", "hi"):			%!h(MISSING)
-	Unknown attribute in the highlight verb:
+	Unknown attribute or style in the highlight verb:
 		Printf("%h[fgGdsds]%s", "hi"):		%!h(BADATTR)
 	String ended before the verb:
 		Printf("%h[fg", "hi"):			%!h(SHORT)
 
+	The same errors are used for the %l verb.
+
 Everything else is handled by the fmt package. You should read its documentation.
 
 Attributes Reference
@@ -50,7 +165,17 @@ Named Colors:
 	%h[xgBrightCyan]
 	%h[xgBrightWhite]
 
-	Where 'x' is either 'f' or 'b'.
+	%h[xgDefault]
+
+	Where 'x' is either 'f' or 'b'. xgDefault restores the terminal's default color.
+
+Underline Colors:
+	%h[ulRed], %h[ul196], %h[ul#rrggbb], %h[ulrgb(r,g,b)] and so on
+	%h[ulDefault]
+
+	Any named, 256 or 24-bit color can be used with the 'ul' prefix to color the
+	underline without recoloring the text. These are only emitted if the terminal
+	advertises the Setulc extended terminfo capability.
 
 256 Colors:
 	%h[fgx]
@@ -58,6 +183,25 @@ Named Colors:
 
 	Where x is any number from 0-255.
 
+24-bit Colors:
+	%h[fg#rrggbb]
+	%h[bg#rrggbb]
+	%h[fg#rgb]
+	%h[bg#rgb]
+	%h[fgrgb(r,g,b)]
+	%h[bgrgb(r,g,b)]
+
+	Where rr, gg and bb are hexadecimal and r, g and b are decimal numbers from 0-255.
+	These are only emitted if the terminal supports direct color, as advertised by the
+	RGB or Tc terminfo extensions or by setting COLORTERM to truecolor or 24bit.
+
+Downsampling:
+
+If the terminal supports fewer colors than an attribute requires, as reported by its
+max_colors capability, the color is replaced with the perceptually closest color the
+terminal does support. This means the same format string renders sensibly on a
+linux console, an xterm-256color and a truecolor terminal.
+
 Modes:
 	%h[reset] or the %r verb
 	%h[bold]
@@ -65,9 +209,36 @@ Modes:
 	%h[reverse]
 	%h[blink]
 	%h[dim]
+	%h[italic]
+	%h[invisible]
+	%h[standout]
+	%h[strike]
+	%h[doubleunderline]
+	%h[curlyunderline]
+	%h[overline]
+
+	The last four use the smxx, Smulx and Smol extended terminfo capabilities.
+	Modes the terminal lacks are omitted.
+
+Turning Modes Off:
+	%h[nobold]
+	%h[nodim]
+	%h[noitalic]
+	%h[nounderline]
+	%h[noblink]
+	%h[noreverse]
+	%h[nostandout]
+	%h[noinvisible]
+	%h[nostrike]
+	%h[nooverline]
+
+	Unlike %r, these only turn off the given mode and leave the others and the colors
+	alone. nobold and nodim both turn off bold and dim as terminals use one code for both.
 
 See http://goo.gl/LRLA7o for information on the attributes. Scroll down to the SGR section.
 
 See http://goo.gl/fvtHLs and ISO-8613-3 (according to above document) for more information on 256 colors.
+
+See ISO-8613-6 for more information on 24-bit colors.
 */
 package color
@@ -1,7 +1,9 @@
 package color_test
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/nhooyr/color"
 )
@@ -24,6 +26,12 @@ func Example_attributes() {
 
 	// "panic:" using color 158 as the background then normal "bar".
 	color.Printf("%h[bg158]panic:%r %s\n", "bar")
+
+	// "panic:" using the 24-bit color #ff8800 as the foreground then normal "foo".
+	color.Printf("%h[fg#ff8800]panic:%r %s\n", "foo")
+
+	// "panic:" using the 24-bit color rgb(12,34,56) as the background then normal "bar".
+	color.Printf("%h[bgrgb(12,34,56)]panic:%r %s\n", "bar")
 }
 
 func Example_mixing() {
@@ -34,6 +42,44 @@ func Example_mixing() {
 	color.Printf("%h[bg8+underline]panic:%r %s\n", "bar")
 }
 
+func ExampleDefineStyle() {
+	// Register the attributes once under a name.
+	color.DefineStyle("error", "fgRed+bold")
+
+	// Bolded "error:" with a red foreground then normal "foo".
+	color.Printf("%h[@error]error:%r %s\n", "foo")
+
+	// Redefining the style changes it everywhere it is used.
+	color.DefineStyle("error", "fgMagenta+underline")
+	color.Printf("%h[@error]error:%r %s\n", "bar")
+}
+
+func ExampleLoadTheme() {
+	theme, err := color.LoadTheme(strings.NewReader(`
+		# Users can ship their own color schemes.
+		error = fgRed+bold
+		path  = fg33+underline
+	`))
+	if err != nil {
+		// err describes the first invalid line.
+		panic(err)
+	}
+	theme.Use()
+
+	// Bolded "error:" with a red foreground then "foo" underlined with color 33.
+	color.Printf("%h[@error]error:%r %h[@path]%s%r\n", "foo")
+}
+
+func ExampleStyle() {
+	warn := color.Style{FG: color.Yellow, BG: color.RGB(1, 2, 3), Bold: true}
+
+	// Bolded "warning:" with a yellow foreground and a 24-bit background then normal "foo".
+	color.Println(warn.Wrap("warning:"), "foo")
+
+	// The same as above.
+	color.Printf("%s foo\n", warn.Sprintf("%s:", "warning"))
+}
+
 func ExamplePrepare() {
 	// Prepare only processes the highlight verbs in the string,
 	// letting you print it repeatedly with performance.
@@ -52,6 +98,11 @@ func ExamplePrepare() {
 	color.Println(hello)
 	color.Println(hello)
 	color.Println(hello)
+
+	// Formats can also be printed by the fmt package, e.g. as fields of a struct.
+	// "HELLO" padded to 8 visible characters, colored if standard output is a terminal.
+	color.SetStringLevel(color.Detect(os.Stdout))
+	fmt.Printf("%-8s|\n", hello)
 }
 
 func ExamplePrinter() {
@@ -62,6 +113,14 @@ func ExamplePrinter() {
 	p := color.New(os.Stderr, color.IsTerminal(os.Stderr))
 	p.Printfp(redFormat, "bar")
 
+	// The same as above but also respects NO_COLOR, FORCE_COLOR, CLICOLOR and CLICOLOR_FORCE.
+	p = color.NewLevel(os.Stderr, color.Detect(os.Stderr))
+	p.Printfp(redFormat, "bar")
+
+	// "foo" with red foreground, downsampled to the 16 named colors.
+	p = color.NewLevel(os.Stderr, color.Basic16)
+	p.Printfp(redFormat, "foo")
+
 	// "foo" with red foreground.
 	p = color.New(os.Stderr, true)
 	p.Printfp(redFormat, "foo")
@@ -69,4 +128,11 @@ func ExamplePrinter() {
 	// Normal "bar", the highlight verbs are ignored.
 	p = color.New(os.Stderr, false)
 	p.Printfp(redFormat, "bar")
+
+	// "foo" with red foreground, using the control sequences of the linux console
+	// regardless of TERM.
+	if t, err := color.LoadTerminal("linux"); err == nil {
+		p = color.NewTerminal(os.Stderr, t, t.Level())
+		p.Printfp(redFormat, "foo")
+	}
 }
@@ -1,50 +1,269 @@
 package color
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
 
 // Format represents a format string with the highlight verbs fully parsed.
+// The format string is compiled once into segments and the string for each level
+// is rendered from them on first use. The strings are rendered again if a style
+// they use is redefined.
 type Format struct {
-	colored  string // highlight verbs replaced with their escape sequences
-	stripped string // highlight verbs stripped
+	t      *Terminal                     // terminal the cached strings are for
+	render func(*Terminal, Level) string // renders the string for any terminal and level
+
+	mu       sync.Mutex
+	gen      uint64                // generation of the styles the cached strings were rendered with
+	rendered [TrueColor + 1]bool   // whether the string for each level is cached
+	levels   [TrueColor + 1]string // rendering of the format string for each level
 }
 
 // Prepare returns a Format structure using f as the base string.
+// It is a thin wrapper around the Prepare method of the terminal returned by DefaultTerminal.
 func Prepare(f string) *Format {
-	return &Format{Highlight(f), Strip(f)}
+	return defaultTerminal.Prepare(f)
+}
+
+// newFormat returns a Format for t with each string rendered by render.
+func newFormat(t *Terminal, render func(*Terminal, Level) string) *Format {
+	return &Format{t: t, render: render}
+}
+
+// newCompiledFormat returns a Format for t that renders the compiled format string f.
+func newCompiledFormat(t *Terminal, f string) *Format {
+	segs := compile(f)
+	scoped := popsScope(f)
+	return newFormat(t, func(t *Terminal, l Level) string {
+		return render(segs, scoped, t, l)
+	})
 }
 
 // Get returns the colored string if color is true, and the stripped string otherwise.
+// The colored string is the one for the level of color support reported by TermLevel.
 func (f *Format) Get(color bool) string {
-	if color {
-		return f.colored
+	return f.GetLevel(levelOf(color))
+}
+
+// GetLevel returns the string for the level of color support l.
+func (f *Format) GetLevel(l Level) string {
+	return f.GetTerminal(f.t, l)
+}
+
+// GetTerminal returns the string for the terminal t and the level of color support l.
+// The string is rendered on demand if f was not prepared for t.
+func (f *Format) GetTerminal(t *Terminal, l Level) string {
+	if l < None {
+		l = None
+	} else if l > TrueColor {
+		l = TrueColor
+	}
+	if t != f.t {
+		return f.render(t, l)
+	}
+	gen := stylesGen()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gen != gen {
+		f.gen = gen
+		f.rendered = [TrueColor + 1]bool{}
+	}
+	if !f.rendered[l] {
+		f.levels[l] = f.render(t, l)
+		f.rendered[l] = true
 	}
-	return f.stripped
+	return f.levels[l]
+}
+
+// stringLevel is the level of color support used by String and Format.
+var stringLevel = int32(None)
+
+// SetStringLevel sets the level of color support of the strings Formats return from
+// their String and Format methods, which are used whenever a Format is printed by
+// the fmt package or by a Printer that does not see it as a top-level argument,
+// e.g. when it is a field of a struct. The default is None.
+func SetStringLevel(l Level) {
+	atomic.StoreInt32(&stringLevel, int32(l))
+}
+
+// StringLevel returns the level of color support set by SetStringLevel.
+func StringLevel() Level {
+	return Level(atomic.LoadInt32(&stringLevel))
+}
+
+// String returns the string for the level of color support returned by StringLevel.
+func (f *Format) String() string {
+	return f.GetLevel(StringLevel())
+}
+
+// Format implements fmt.Formatter. The %s and %v verbs print the string returned by
+// String, truncated to the precision and then padded to the width. Both are measured
+// in columns, so the control sequences do not count towards them and wide characters
+// count twice. Other verbs, like %q, format the string as fmt would.
+func (f *Format) Format(st fmt.State, verb rune) {
+	formatString(st, verb, f.String())
 }
 
 // Eprintfp calls fmt.Sprintf using f's strings and the rest of the arguments.
 // It will expand each Format in a to its appropriate string before calling Sprintf.
 // It then returns the resulting Format.
+// The arguments are not modified. Those that are not Formats are formatted right away,
+// so changing them afterwards does not change the strings of the resulting Format.
 func (f *Format) Eprintfp(a ...interface{}) *Format {
-	m := make(map[int]*Format)
+	a = freezeArgs(f.GetTerminal(f.t, None), a)
+	return newFormat(f.t, func(t *Terminal, l Level) string {
+		return fmt.Sprintf(f.GetTerminal(t, l), ExpandFormatsTerminal(t, l, a)...)
+	})
+}
+
+// freezeArgs returns a copy of a with each argument that is not a Format formatted by
+// its directive in the format string f. The strings rendered from the copy then do not
+// depend on the arguments, except for the Formats which are expanded when rendering.
+func freezeArgs(f string, a []interface{}) []interface{} {
+	a = append([]interface{}(nil), a...)
+	verbs := argVerbs(f)
 	for i, v := range a {
-		if f, ok := v.(*Format); ok {
-			a[i], m[i] = f.Get(true), f
+		if i >= len(verbs) {
+			break
 		}
+		// Directives with a '*' also take the width or precision from the arguments.
+		if _, ok := v.(*Format); ok || strings.IndexByte(verbs[i], '*') >= 0 {
+			continue
+		}
+		a[i] = formatted(fmt.Sprintf(verbs[i], v))
 	}
-	rf := new(Format)
-	rf.colored = fmt.Sprintf(f.colored, a...)
-	for i, f := range m {
-		a[i] = f.Get(false)
+	return a
+}
+
+// formatted is an argument that was already formatted by its directive.
+type formatted string
+
+// Format implements fmt.Formatter by writing s unchanged.
+func (s formatted) Format(st fmt.State, verb rune) {
+	io.WriteString(st, string(s))
+}
+
+// argVerbs returns the fmt directive that formats each argument of the format string f,
+// or "*" for the arguments used as a width or precision. It returns nil if f uses
+// explicit argument indexes, as the arguments are then not used in order.
+func argVerbs(f string) []string {
+	var verbs []string
+	for i := 0; i < len(f); {
+		if f[i] != '%' {
+			i++
+			continue
+		}
+		start := i
+		for i++; i < len(f); i++ {
+			c := f[i]
+			if c == '[' {
+				return nil
+			}
+			if c == '*' {
+				verbs = append(verbs, "*")
+			} else if !strings.ContainsRune("+-# 0.", rune(c)) && (c < '1' || c > '9') {
+				break
+			}
+		}
+		if i == len(f) {
+			break
+		}
+		verb, n := utf8.DecodeRuneInString(f[i:])
+		i += n
+		if verb != '%' {
+			verbs = append(verbs, f[start:i])
+		}
 	}
-	rf.stripped = fmt.Sprintf(f.stripped, a...)
-	return rf
+	return verbs
 }
 
 // ExpandFormats replaces each Format in a with its appropriate string according to color.
+//
+// Deprecated: ExpandFormats modifies a, so it cannot be reused for other Printers.
+// Use ExpandFormatsCopy instead.
 func ExpandFormats(color bool, a []interface{}) {
+	l := levelOf(color)
 	for i, v := range a {
 		if f, ok := v.(*Format); ok {
-			a[i] = f.Get(color)
+			a[i] = f.GetLevel(l)
 		}
 	}
 }
+
+// ExpandFormatsCopy returns a copy of a with each Format replaced by its appropriate string
+// according to color. The slice a is not modified, so it can be reused for other Printers.
+func ExpandFormatsCopy(color bool, a []interface{}) []interface{} {
+	return ExpandFormatsLevel(levelOf(color), a)
+}
+
+// ExpandFormatsLevel returns a copy of a with each Format replaced by its string for the
+// level of color support l.
+func ExpandFormatsLevel(l Level, a []interface{}) []interface{} {
+	return ExpandFormatsTerminal(defaultTerminal, l, a)
+}
+
+// ExpandFormatsTerminal returns a copy of a with each Format replaced by its string for
+// the terminal t and the level of color support l.
+func ExpandFormatsTerminal(t *Terminal, l Level, a []interface{}) []interface{} {
+	return AppendFormats(make([]interface{}, 0, len(a)), t, l, a...)
+}
+
+// AppendFormats appends a to dst with each Format replaced by its string for the terminal t
+// and the level of color support l, and returns the extended slice.
+// Reusing dst between calls avoids allocating a new slice each time.
+func AppendFormats(dst []interface{}, t *Terminal, l Level, a ...interface{}) []interface{} {
+	for _, v := range a {
+		if f, ok := v.(*Format); ok {
+			v = f.GetTerminal(t, l)
+		}
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+// argsPool holds slices for the expanded arguments of Printers.
+var argsPool = sync.Pool{
+	New: func() interface{} {
+		return new([]interface{})
+	},
+}
+
+// expandArgs returns a pooled copy of a with each Format replaced by its string for
+// the terminal t and the level of color support l. The strings formatted by a %s or %v
+// directive of verbs with a width or precision are wrapped if fmt would pad them
+// incorrectly, such as those with control sequences or wide characters, so that they
+// are padded by their width in columns. Return the copy with freeArgs.
+func expandArgs(t *Terminal, l Level, verbs []string, a []interface{}) *[]interface{} {
+	args := argsPool.Get().(*[]interface{})
+	*args = AppendFormats((*args)[:0], t, l, a...)
+	for i, v := range *args {
+		if i >= len(verbs) {
+			break
+		}
+		if s, ok := v.(string); ok && padsString(verbs[i]) && needsPadding(s) {
+			(*args)[i] = padded(s)
+		}
+	}
+	return args
+}
+
+// padsString reports whether the directive verb is %s or %v with a width or precision.
+func padsString(verb string) bool {
+	n := len(verb) - 1
+	if verb[n] != 's' && verb[n] != 'v' {
+		return false
+	}
+	return strings.ContainsAny(verb[1:n], "123456789.*")
+}
+
+// freeArgs clears args so it does not keep the arguments alive and returns it to the pool.
+func freeArgs(args *[]interface{}) {
+	for i := range *args {
+		(*args)[i] = nil
+	}
+	argsPool.Put(args)
+}
@@ -1,6 +1,8 @@
 package color
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/nhooyr/terminfo/caps"
@@ -36,6 +38,81 @@ func TestEprintf(t *testing.T) {
 	}
 }
 
+func TestEprintfArgs(t *testing.T) {
+	t.Parallel()
+	inner := Prepare("%h[fgGreen]rip")
+	a := []interface{}{"bar", inner}
+	f := Prepare("%h[fgRed]panic: %s: %s").Eprintfp(a...)
+	exp := "panic: bar: rip"
+	if r := f.Get(false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = Highlight("%h[fgRed]panic: bar: %h[fgGreen]rip")
+	if r := f.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if a[1] != inner {
+		t.Errorf("Expected the arguments to be left unchanged but result was %v", a)
+	}
+	f2 := Prepare("%s: %s").Eprintfp(a...)
+	if r := f2.Get(false); r != "bar: rip" {
+		t.Errorf("Expected %q but result was %q", "bar: rip", r)
+	}
+	nums := []int{1, 2}
+	a = []interface{}{"bar", nums, inner}
+	f3 := Prepare("%h[fgRed]%s %v %s|").Eprintfp(a...)
+	a[0] = "baz"
+	nums[0] = 3
+	if r := f3.Get(false); r != "bar [1 2] rip|" {
+		t.Errorf("Expected %q but result was %q", "bar [1 2] rip|", r)
+	}
+	exp = Highlight("%h[fgRed]bar [1 2] %h[fgGreen]rip|")
+	if r := f3.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+var argVerbTests = map[string][]string{
+	"":                  nil,
+	"100%% %s":          {"%s"},
+	"%-20s|%5.2f %T %x": {"%-20s", "%5.2f", "%T", "%x"},
+	"%*d %.*s":          {"*", "%*d", "*", "%.*s"},
+	"%[2]s %[1]s":       nil,
+	"%s%":               {"%s"},
+}
+
+func TestArgVerbs(t *testing.T) {
+	t.Parallel()
+	for f, exp := range argVerbTests {
+		if r := argVerbs(f); !reflect.DeepEqual(r, exp) {
+			t.Errorf("Expected %q from %q but result was %q", exp, f, r)
+		}
+	}
+}
+
+func TestRedefineStyleFormat(t *testing.T) {
+	t.Parallel()
+	if err := DefineStyle("testFormat", "fgRed"); err != nil {
+		t.Fatal(err)
+	}
+	f := Prepare("%h[@testFormat]hi")
+	outer := Prepare("%s!").Eprintfp(f)
+	exp := Highlight("%h[fgRed]hi")
+	if r := f.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if err := DefineStyle("testFormat", "fgBlue+bold"); err != nil {
+		t.Fatal(err)
+	}
+	exp = Highlight("%h[fgBlue+bold]hi")
+	if r := f.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := outer.Get(true); r != exp+"!" {
+		t.Errorf("Expected %q but result was %q", exp+"!", r)
+	}
+}
+
 func TestExpandFormats(t *testing.T) {
 	t.Parallel()
 	a := [3]interface{}{
@@ -45,15 +122,110 @@ func TestExpandFormats(t *testing.T) {
 	}
 	exp := a
 	exp[0] = ti.Color(-1, caps.Magenta) + "foo"
-	r := a
-	ExpandFormats(true, r[:])
+	orig := a
+	var r [3]interface{}
+	copy(r[:], ExpandFormatsCopy(true, a[:]))
 	if exp != r {
 		t.Errorf("Expected %q but result was %q", exp, r)
 	}
 	exp[0] = "foo"
-	r = a
-	ExpandFormats(false, r[:])
+	copy(r[:], ExpandFormatsCopy(false, a[:]))
 	if exp != r {
 		t.Errorf("Expected %q but result was %q", exp, r)
 	}
+	if a != orig {
+		t.Errorf("Expected the arguments to be left unchanged but result was %v", a)
+	}
+	ExpandFormats(false, a[:])
+	if exp != a {
+		t.Errorf("Expected %q but result was %q", exp, a)
+	}
+}
+
+func TestAppendFormats(t *testing.T) {
+	t.Parallel()
+	f := Prepare("%h[bold]foo")
+	dst := []interface{}{"bar"}
+	r := AppendFormats(dst, defaultTerminal, None, f, 3)
+	exp := []interface{}{"bar", "foo", 3}
+	if len(r) != len(exp) {
+		t.Fatalf("Expected %v but result was %v", exp, r)
+	}
+	for i := range exp {
+		if r[i] != exp[i] {
+			t.Errorf("Expected %v but result was %v", exp, r)
+			break
+		}
+	}
+}
+
+func TestGetLevel(t *testing.T) {
+	t.Parallel()
+	f := Prepare("%h[fg196+bg#ff8800]foo")
+	for l := None; l <= TrueColor; l++ {
+		exp := RunLevel("%h[fg196+bg#ff8800]foo", l)
+		if r := f.GetLevel(l); r != exp {
+			t.Errorf("Expected %q at %v but result was %q", exp, l, r)
+		}
+	}
+	if r := f.GetLevel(None); r != "foo" {
+		t.Errorf("Expected %q but result was %q", "foo", r)
+	}
+	if r := f.Get(true); r != f.GetLevel(TermLevel()) {
+		t.Errorf("Expected %q but result was %q", f.GetLevel(TermLevel()), r)
+	}
+}
+
+func TestFormatString(t *testing.T) {
+	f := Prepare("%h[fgRed]hi%r")
+	if r := f.String(); r != "hi" {
+		t.Errorf("Expected %q but result was %q", "hi", r)
+	}
+	SetStringLevel(Ansi256)
+	defer SetStringLevel(None)
+	exp := f.GetLevel(Ansi256)
+	if r := f.String(); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = fmt.Sprintf("[%s]", exp)
+	if r := fmt.Sprint([]*Format{f}); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = "  " + f.GetLevel(Ansi256) + "|"
+	if r := fmt.Sprintf("%4s|", f); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+var formatVerbs = map[string]string{
+	"%s":     "hi",
+	"%v":     "hi",
+	"%5s|":   "   hi|",
+	"%-5v|":  "hi   |",
+	"%05s":   "000hi",
+	"%.1s":   "h",
+	"%5.1s|": "    h|",
+	"%1s":    "hi",
+	"%q":     `"hi"`,
+	"%x":     "6869",
+	"%+v":    "hi",
+	"{%v}":   "{hi}",
+	"%8q|":   `    "hi"|`,
+}
+
+func TestFormatFormatter(t *testing.T) {
+	t.Parallel()
+	f := Prepare("%h[bold]hi")
+	for k, v := range formatVerbs {
+		if r := fmt.Sprintf(k, f); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+	type field struct {
+		F *Format
+	}
+	exp := "{hi}"
+	if r := fmt.Sprintf("%v", field{f}); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
 }
@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
 
-	"github.com/nhooyr/terminfo"
 	"github.com/nhooyr/terminfo/caps"
 )
 
@@ -19,13 +19,35 @@ const (
 
 // highlighter holds the state of the scanner.
 type highlighter struct {
-	s     string        // string being scanned
-	pos   int           // position in s
-	buf   *bytes.Buffer // where result is built
-	color bool          // color or strip the highlight verbs
-	fg    bool          // foreground or background color attribute
+	s      string        // string being scanned
+	pos    int           // position in s
+	buf    *bytes.Buffer // where result is built
+	t      *Terminal     // terminal the control sequences are for
+	level  Level         // level of color support
+	color  bool          // color or strip the highlight verbs
+	colors int           // number of colors in the palette
+	rgb    bool          // whether 24-bit colors are supported
+	target colorTarget   // what a color attribute colors
+	style  bool          // scanning the attributes of a style
+	err    string        // error written, if any
+
+	scoped bool     // whether s pops scopes with %h[/]
+	stack  []string // control sequences of the open scopes
+	frame  int      // position in buf where the current scope's control sequences begin
+
+	link    string // target of the open hyperlink
+	linkAlt bool   // whether to append the target when stripping the open hyperlink
 }
 
+// colorTarget identifies what a color attribute colors.
+type colorTarget int
+
+const (
+	targetFG colorTarget = iota // foreground
+	targetBG                    // background
+	targetUL                    // underline
+)
+
 // highlighterPool allows the reuse of highlighters to avoid allocations.
 var highlighterPool = sync.Pool{
 	New: func() interface{} {
@@ -36,25 +58,65 @@ var highlighterPool = sync.Pool{
 	},
 }
 
-// Global terminfo struct.
-var ti, tiErr = terminfo.LoadEnv()
-
 // newHighlighter returns a new initialized highlighter from the pool.
-func newHighlighter(s string, color bool) *highlighter {
+func newHighlighter(s string, t *Terminal, l Level) *highlighter {
 	hl := highlighterPool.Get().(*highlighter)
 	hl.s = s
-	if tiErr != nil {
-		hl.color = false
-	} else {
-		hl.color = color
+	hl.t = t
+	hl.scoped = popsScope(s)
+	if t.ti == nil {
+		l = None
+	}
+	hl.level = l
+	hl.color = l > None
+	hl.colors = t.maxColors
+	switch {
+	case l == Basic16 && hl.colors > 16:
+		hl.colors = 16
+	case l == Ansi256 && hl.colors > 256:
+		hl.colors = 256
 	}
+	hl.rgb = l == TrueColor && t.trueColor
 	return hl
 }
 
+// popsScope reports whether s contains a %h[/] verb. Escaped percent signs are skipped
+// so that "%%h[/]" does not count.
+func popsScope(s string) bool {
+	for {
+		i := strings.IndexByte(s, '%')
+		if i < 0 || i+1 == len(s) {
+			return false
+		}
+		s = s[i:]
+		if s[1] == '%' {
+			s = s[2:]
+			continue
+		}
+		if strings.HasPrefix(s, "%h[/]") {
+			return true
+		}
+		s = s[1:]
+	}
+}
+
+// levelOf returns the level of color support for a color argument.
+func levelOf(color bool) Level {
+	if color {
+		return TermLevel()
+	}
+	return None
+}
+
 // free resets the highlighter.
 func (hl *highlighter) free() {
 	hl.buf.Reset()
 	hl.pos = 0
+	hl.t = nil
+	hl.style = false
+	hl.err = ""
+	hl.stack = hl.stack[:0]
+	hl.link, hl.linkAlt = "", false
 	highlighterPool.Put(hl)
 }
 
@@ -74,10 +136,18 @@ func Strip(s string) string {
 // Run runs a highlighter with s as the input and then returns the output. The color argument
 // determines whether the highlight verbs will be replaced with their appropriate control
 // sequences or instead stripped.
+// It is a thin wrapper around RunLevel that uses the level of color support reported by
+// TermLevel if color is true and None otherwise.
 func Run(s string, color bool) string {
-	hl := newHighlighter(s, color)
-	defer hl.free()
-	return hl.run()
+	return RunLevel(s, levelOf(color))
+}
+
+// RunLevel is the same as Run but takes the level of color support. Colors are downsampled
+// to the closest ones supported by both l and the terminal. The highlight verbs are stripped
+// if l is None.
+// It is a thin wrapper around the Run method of the terminal returned by DefaultTerminal.
+func RunLevel(s string, l Level) string {
+	return defaultTerminal.Run(s, l)
 }
 
 // stateFn represents the state of the scanner as a function that returns the next state.
@@ -111,10 +181,41 @@ func (hl *highlighter) writeFrom(ppos int) {
 	}
 }
 
+// error writes the error e to the buffer and records it.
+func (hl *highlighter) error(e string) {
+	hl.err = e
+	hl.buf.WriteString(e)
+}
+
 func (hl *highlighter) writeAttr(a string) {
 	hl.buf.WriteString(a)
 }
 
+// writeColor writes the control sequence that sets the foreground, background
+// or underline to the palette color c. If the terminal does not support c, the
+// closest color it does support is used instead.
+func (hl *highlighter) writeColor(c int) {
+	if hl.colors <= 0 {
+		// The terminal has no colors.
+		return
+	}
+	if c >= hl.colors {
+		c = fitPaletteColor(c, hl.colors)
+	}
+	if hl.target == targetUL {
+		// Colored underlines are set with SGR 58 as terminfo has no standard capability.
+		if hl.t.ulColor {
+			hl.writeAttr("\x1b[58;5;" + strconv.Itoa(c) + "m")
+		}
+		return
+	}
+	if hl.target == targetFG {
+		hl.writeAttr(hl.t.color(c, -1))
+	} else {
+		hl.writeAttr(hl.t.color(-1, c))
+	}
+}
+
 // scanAttribute returns the string from the current character to
 // the start of the next attribute or end of the verb.
 func (hl *highlighter) scanAttribute() (string, error) {
@@ -163,60 +264,191 @@ func scanVerb(hl *highlighter) stateFn {
 	switch ch {
 	case 'r':
 		if hl.color {
-			hl.writeAttr(ti.Strings[caps.ExitAttributeMode])
+			hl.writeAttr(hl.t.str(caps.ExitAttributeMode))
 		}
+		hl.stack = hl.stack[:0]
 		return scanText
 	case 'h':
 		// Ensure next character is '['.
 		ch, err = hl.get()
 		if err != nil {
-			hl.buf.WriteString(errShort)
+			hl.error(errShort)
 			return nil
 		}
 		if ch != '[' {
-			hl.buf.WriteString(errInvalid)
+			hl.error(errInvalid)
 			return nil
 		}
 		// Ensure next character is not ']'.
 		hl.pos++
 		ch, err = hl.get()
 		if err != nil {
-			hl.buf.WriteString(errShort)
+			hl.error(errShort)
 			return nil
 		}
 		if ch == ']' {
-			hl.buf.WriteString(errMissing)
+			hl.error(errMissing)
 			return nil
 		}
+		if ch == '/' && strings.HasPrefix(hl.s[hl.pos:], "/]") {
+			hl.pos += 2
+			hl.pop()
+			return scanText
+		}
+		hl.frame = hl.buf.Len()
 		return startAttribute
+	case 'l':
+		return scanLink
+	case 'L':
+		hl.endLink()
+		return scanText
+	case '#':
+		if ch, err = hl.get(); err == nil && ch == 'l' {
+			hl.pos++
+			hl.linkAlt = true
+			return scanLink
+		}
+		ch = '#'
 	}
 	// Include the verb.
 	hl.writePrev(2)
+	if hl.scoped && ch != '%' {
+		// The argument may change the attributes, so restore those of the open scopes.
+		hl.scanDirective(ch)
+		hl.restore()
+	}
+	return scanText
+}
+
+// scanLink scans the target of a hyperlink verb and opens the hyperlink.
+func scanLink(hl *highlighter) stateFn {
+	ch, err := hl.get()
+	if err != nil {
+		hl.error(errShort)
+		return nil
+	}
+	if ch != '[' {
+		hl.error(errInvalid)
+		return nil
+	}
+	hl.pos++
+	end := strings.IndexByte(hl.s[hl.pos:], ']')
+	if end < 0 {
+		hl.error(errShort)
+		return nil
+	}
+	if end == 0 {
+		hl.error(errMissing)
+		return nil
+	}
+	hl.link = hl.s[hl.pos : hl.pos+end]
+	hl.pos += end + 1
+	if hl.color {
+		hl.writeAttr("\x1b]8;;")
+		hl.writeEscaped(hl.link)
+		hl.writeAttr("\x1b\\")
+	}
 	return scanText
 }
 
+// endLink closes the open hyperlink.
+func (hl *highlighter) endLink() {
+	if hl.color {
+		hl.writeAttr("\x1b]8;;\x1b\\")
+	} else if hl.linkAlt && hl.link != "" {
+		hl.buf.WriteString(" (")
+		hl.writeEscaped(hl.link)
+		hl.buf.WriteByte(')')
+	}
+	hl.link, hl.linkAlt = "", false
+}
+
+// writeEscaped writes s with each % doubled so that fmt prints it verbatim.
+func (hl *highlighter) writeEscaped(s string) {
+	for {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			hl.buf.WriteString(s)
+			return
+		}
+		hl.buf.WriteString(s[:i+1])
+		hl.buf.WriteByte('%')
+		s = s[i+1:]
+	}
+}
+
+// scanDirective writes the rest of the fmt directive beginning with ch,
+// e.g. "-20s" in "%-20s".
+func (hl *highlighter) scanDirective(ch byte) {
+	ppos := hl.pos
+	for !isVerb(ch) {
+		var err error
+		if ch, err = hl.get(); err != nil {
+			break
+		}
+		hl.pos++
+	}
+	hl.writeFrom(ppos)
+}
+
+// isVerb reports whether ch ends a fmt directive.
+func isVerb(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// push opens a scope with the control sequences written since the start of the verb.
+func (hl *highlighter) push() {
+	if hl.scoped && hl.color {
+		hl.stack = append(hl.stack, string(hl.buf.Bytes()[hl.frame:]))
+	}
+}
+
+// pop closes the innermost scope by resetting all attributes and then
+// restoring those of the remaining scopes.
+func (hl *highlighter) pop() {
+	if !hl.color {
+		return
+	}
+	hl.writeAttr(hl.t.str(caps.ExitAttributeMode))
+	if len(hl.stack) > 0 {
+		hl.stack = hl.stack[:len(hl.stack)-1]
+	}
+	hl.restore()
+}
+
+// restore writes the control sequences of the open scopes.
+func (hl *highlighter) restore() {
+	for _, a := range hl.stack {
+		hl.writeAttr(a)
+	}
+}
+
 // startAttribute checks the type of the attribute and passes control appropriately.
 func startAttribute(hl *highlighter) stateFn {
 	// No need to check error because the character was already read.
+	next := byte('g')
 	switch ch, _ := hl.get(); ch {
 	case 'f':
-		hl.fg = true
+		hl.target = targetFG
 	case 'b':
-		hl.fg = false
+		hl.target = targetBG
+	case 'u':
+		hl.target = targetUL
+		next = 'l'
 	default:
 		return scanMode
 	}
-	// Attribute starts with 'f' or 'b' so it could be a color attribute.
+	// Attribute starts with 'f', 'b' or 'u' so it could be a color attribute.
 	// Rest of the code confirms if it is a color attribute, and if so,
 	// whether it is a named or a 256 color attribute.
 	hl.pos++
 	ch, err := hl.get()
 	if err != nil {
-		hl.buf.WriteString(errShort)
+		hl.error(errShort)
 		return nil
 	}
-	if ch != 'g' {
-		// Actually a mode attribute, because color attributes must begin with "fg" or "bg".
+	if ch != next {
+		// Actually a mode attribute, because color attributes must begin with "fg", "bg" or "ul".
 		hl.pos--
 		return scanMode
 	}
@@ -224,11 +456,14 @@ func startAttribute(hl *highlighter) stateFn {
 	hl.pos++
 	ch, err = hl.get()
 	if err != nil {
-		hl.buf.WriteString(errShort)
+		hl.error(errShort)
 		return nil
 	}
-	if ch >= '0' && ch <= '9' {
+	switch {
+	case ch >= '0' && ch <= '9':
 		return scanColor256
+	case ch == '#' || ch == 'r':
+		return scanColorRGB
 	}
 	return scanColor
 }
@@ -241,22 +476,80 @@ var modes = map[string]int16{
 	"reverse":   caps.EnterReverseMode,
 	"blink":     caps.EnterBlinkMode,
 	"dim":       caps.EnterDimMode,
+	"italic":    caps.EnterItalicsMode,
+	"invisible": caps.EnterSecureMode,
+	"standout":  caps.EnterStandoutMode,
 }
 
-// scanAttribute scans a mode attribute.
+// extMode describes a mode that is set with an extended capability.
+type extMode struct {
+	name  string // name of the capability
+	param int    // parameter of the capability, if it takes one
+}
+
+// extModes maps mode names to their extended capabilities.
+var extModes = map[string]extMode{
+	"strike":          {"smxx", 0},
+	"doubleunderline": {"Smulx", 2},
+	"curlyunderline":  {"Smulx", 3},
+	"overline":        {"Smol", 0},
+}
+
+// offModes maps the names of modes that turn off other modes to their SGR codes.
+// Terminfo has no capabilities for most of these.
+var offModes = map[string]string{
+	"nobold":      "\x1b[22m",
+	"nodim":       "\x1b[22m",
+	"noitalic":    "\x1b[23m",
+	"nounderline": "\x1b[24m",
+	"noblink":     "\x1b[25m",
+	"noreverse":   "\x1b[27m",
+	"nostandout":  "\x1b[27m",
+	"noinvisible": "\x1b[28m",
+	"nostrike":    "\x1b[29m",
+	"nooverline":  "\x1b[55m",
+}
+
+// scanMode scans a mode attribute or the name of a style.
 func scanMode(hl *highlighter) stateFn {
 	a, err := hl.scanAttribute()
 	if err != nil {
-		hl.buf.WriteString(errShort)
+		hl.error(errShort)
 		return nil
 	}
 	if n, ok := modes[a]; ok {
 		if hl.color {
-			hl.writeAttr(ti.Strings[n])
+			hl.writeAttr(hl.t.str(n))
+		}
+		if n == caps.ExitAttributeMode {
+			// Resetting closes all scopes, including the rest of this verb's.
+			hl.stack = hl.stack[:0]
+			hl.frame = hl.buf.Len()
+		}
+		return endAttribute
+	}
+	if m, ok := extModes[a]; ok {
+		if hl.color {
+			hl.writeAttr(hl.t.extString(m))
+		}
+		return endAttribute
+	}
+	if sgr, ok := offModes[a]; ok {
+		if hl.color {
+			hl.writeAttr(sgr)
 		}
 		return endAttribute
 	}
-	hl.buf.WriteString(errBadAttr)
+	// Styles cannot refer to other styles.
+	if !hl.style {
+		if attrs, ok := lookupStyle(strings.TrimPrefix(a, "@")); ok {
+			if hl.color {
+				hl.writeAttr(runStyle(attrs, hl.t, hl.level))
+			}
+			return endAttribute
+		}
+	}
+	hl.error(errBadAttr)
 	return nil
 }
 
@@ -282,59 +575,154 @@ var colors = map[string]int{
 
 // scanColor scans a named color attribute.
 func scanColor(hl *highlighter) stateFn {
+	start := hl.pos - 2
 	a, err := hl.scanAttribute()
 	if err != nil {
-		hl.buf.WriteString(errShort)
+		hl.error(errShort)
 		return nil
 	}
 	if c, ok := colors[a]; ok {
 		if hl.color {
-			if hl.fg {
-				hl.writeAttr(ti.Color(c, -1))
-			} else {
-				hl.writeAttr(ti.Color(-1, c))
+			hl.writeColor(c)
+		}
+		return endAttribute
+	}
+	if a == "Default" {
+		if hl.color {
+			switch hl.target {
+			case targetFG:
+				hl.writeAttr("\x1b[39m")
+			case targetBG:
+				hl.writeAttr("\x1b[49m")
+			case targetUL:
+				if hl.t.ulColor {
+					hl.writeAttr("\x1b[59m")
+				}
 			}
 		}
 		return endAttribute
 	}
-	hl.buf.WriteString(errBadAttr)
-	return nil
+	return hl.notColor(start)
+}
+
+// notColor scans the attribute at start again as a mode. It starts with "fg", "bg" or
+// "ul" but is not a color, so it can still be a style, e.g. "bgjob".
+func (hl *highlighter) notColor(start int) stateFn {
+	hl.pos = start
+	return scanMode
 }
 
 // scanColor256 scans a 256 color attribute.
 func scanColor256(hl *highlighter) stateFn {
+	start := hl.pos - 2
 	a, err := hl.scanAttribute()
 	if err != nil {
-		hl.buf.WriteString(errShort)
+		hl.error(errShort)
 		return nil
 	}
-	t, err := strconv.Atoi(a)
+	t, err := strconv.ParseUint(a, 10, 8)
+	if err != nil {
+		return hl.notColor(start)
+	}
+	if hl.color {
+		hl.writeColor(int(t))
+	}
+	return endAttribute
+}
+
+// scanColorRGB scans a 24-bit color attribute.
+func scanColorRGB(hl *highlighter) stateFn {
+	start := hl.pos - 2
+	a, err := hl.scanAttribute()
 	if err != nil {
-		hl.buf.WriteString(errBadAttr)
+		hl.error(errShort)
 		return nil
 	}
+	r, g, b, ok := parseRGB(a)
+	if !ok {
+		return hl.notColor(start)
+	}
 	if hl.color {
-		if hl.fg {
-			hl.writeAttr(ti.Color(t, -1))
-		} else {
-			hl.writeAttr(ti.Color(-1, t))
+		switch {
+		case hl.rgb:
+			if hl.target != targetUL || hl.t.ulColor {
+				hl.writeAttr(rgbColor(hl.target, r, g, b))
+			}
+		case hl.colors > 0:
+			hl.writeColor(fitColor(r, g, b, hl.colors))
 		}
 	}
 	return endAttribute
 }
 
+// parseRGB parses a color in either the #rrggbb, #rgb or rgb(r,g,b) forms.
+func parseRGB(a string) (r, g, b uint8, ok bool) {
+	var rgb [3]uint64
+	var err error
+	switch {
+	case len(a) == 7 && a[0] == '#':
+		for i := range rgb {
+			if rgb[i], err = strconv.ParseUint(a[1+i*2:3+i*2], 16, 8); err != nil {
+				return 0, 0, 0, false
+			}
+		}
+	case len(a) == 4 && a[0] == '#':
+		for i := range rgb {
+			if rgb[i], err = strconv.ParseUint(a[1+i:2+i], 16, 8); err != nil {
+				return 0, 0, 0, false
+			}
+			rgb[i] *= 0x11
+		}
+	case strings.HasPrefix(a, "rgb(") && strings.HasSuffix(a, ")"):
+		parts := strings.Split(a[4:len(a)-1], ",")
+		if len(parts) != len(rgb) {
+			return 0, 0, 0, false
+		}
+		for i, p := range parts {
+			if rgb[i], err = strconv.ParseUint(strings.TrimSpace(p), 10, 8); err != nil {
+				return 0, 0, 0, false
+			}
+		}
+	default:
+		return 0, 0, 0, false
+	}
+	return uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), true
+}
+
+// rgbColor returns the ISO-8613-6 control sequence that sets the foreground,
+// background or underline to the given 24-bit color.
+func rgbColor(t colorTarget, r, g, b uint8) string {
+	buf := make([]byte, 0, 19)
+	switch t {
+	case targetFG:
+		buf = append(buf, "\x1b[38;2;"...)
+	case targetBG:
+		buf = append(buf, "\x1b[48;2;"...)
+	case targetUL:
+		buf = append(buf, "\x1b[58;2;"...)
+	}
+	buf = strconv.AppendUint(buf, uint64(r), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendUint(buf, uint64(g), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendUint(buf, uint64(b), 10)
+	buf = append(buf, 'm')
+	return string(buf)
+}
+
 // endAttribute handles the end of attributes. If there is another attribute, control is
 // thrown to scanHighlight, but if the verb has ended, control is thrown to scanText.
 func endAttribute(hl *highlighter) stateFn {
 	ch, _ := hl.get()
 	hl.pos++
 	if ch == ']' {
+		hl.push()
 		return scanText
 	}
 	// Must read the next character here because scanHighlight assumes that
 	// the character was already read. See scanVerb.
 	if _, err := hl.get(); err != nil {
-		hl.buf.WriteString(errShort)
+		hl.error(errShort)
 		return nil
 	}
 	return startAttribute
@@ -2,25 +2,45 @@ package color
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/nhooyr/terminfo/caps"
 )
 
+// The terminfo of the default terminal and its color support, for building expectations.
+var (
+	ti        = defaultTerminal.ti
+	maxColors = defaultTerminal.maxColors
+	trueColor = defaultTerminal.trueColor
+	ulColor   = defaultTerminal.ulColor
+)
+
 func exp(s string) string {
-	if tiErr != nil {
+	if ti == nil {
 		return ""
 	}
 	return s
 }
 
 func expF(f string, s string) string {
-	if tiErr != nil {
+	if ti == nil {
 		return s
 	}
 	return fmt.Sprintf(f, s)
 }
 
+// tiColor is the same as ti.Color but fits the colors to the terminal's palette.
+func tiColor(fg, bg int) string {
+	if fg >= 0 {
+		fg = fitPaletteColor(fg, maxColors)
+	}
+	if bg >= 0 {
+		bg = fitPaletteColor(bg, maxColors)
+	}
+	return ti.Color(fg, bg)
+}
+
 func TestModes(t *testing.T) {
 	t.Parallel()
 	for k, v := range modes {
@@ -32,15 +52,108 @@ func TestModes(t *testing.T) {
 	}
 }
 
+func TestExtModes(t *testing.T) {
+	t.Parallel()
+	for k, v := range extModes {
+		exp := expF(defaultTerminal.extString(v)+"%s"+ti.Strings[caps.ExitAttributeMode], "hi")
+		r := Highlight(fmt.Sprintf("%%h[%s]hi%%r", k))
+		if r != exp {
+			t.Errorf("Expected %q but result was %q", exp, r)
+		}
+	}
+}
+
+func TestOffModes(t *testing.T) {
+	t.Parallel()
+	for k, v := range offModes {
+		exp := expF(ti.Strings[caps.EnterBoldMode]+v+"%s", "hi")
+		r := Highlight(fmt.Sprintf("%%h[bold+%s]hi", k))
+		if r != exp {
+			t.Errorf("Expected %q but result was %q", exp, r)
+		}
+	}
+}
+
+var defaultColors = map[string]string{
+	"%h[fgRed+fgDefault]hi":  exp(tiColor(caps.Red, -1)+"\x1b[39m") + "hi",
+	"%h[bgRed+bgDefault]hi":  exp(tiColor(-1, caps.Red)+"\x1b[49m") + "hi",
+	"%h[fgDefault+bold]hi":   exp("\x1b[39m"+ti.Strings[caps.EnterBoldMode]) + "hi",
+	"%h[bgDefaults]hi":       errBadAttr,
+	"%h[fgRed+nobold+dim]hi": exp(tiColor(caps.Red, -1)+"\x1b[22m"+ti.Strings[caps.EnterDimMode]) + "hi",
+}
+
+func expUL(s string) string {
+	if ti == nil || !ulColor {
+		return ""
+	}
+	return s
+}
+
+func expULRGB(r, g, b uint8) string {
+	if !trueColor {
+		return expUL(fmt.Sprintf("\x1b[58;5;%dm", fitColor(r, g, b, 256)))
+	}
+	return expUL(rgbColor(targetUL, r, g, b))
+}
+
+var underlineColors = map[string]string{
+	"%h[ulRed]hi":                 expUL("\x1b[58;5;1m") + "hi",
+	"%h[ulBrightWhite]hi":         expUL("\x1b[58;5;15m") + "hi",
+	"%h[ul196]hi":                 expUL("\x1b[58;5;196m") + "hi",
+	"%h[ul#ff8800]hi":             expULRGB(255, 136, 0) + "hi",
+	"%h[ulrgb(1,2,3)]hi":          expULRGB(1, 2, 3) + "hi",
+	"%h[ulDefault]hi":             expUL("\x1b[59m") + "hi",
+	"%h[curlyunderline+ulRed]hi":  exp(defaultTerminal.extString(extModes["curlyunderline"])) + expUL("\x1b[58;5;1m") + "hi",
+	"%h[underline+ulRed+fgRed]hi": exp(ti.Strings[caps.EnterUnderlineMode]) + expUL("\x1b[58;5;1m") + exp(tiColor(caps.Red, -1)) + "hi",
+	"%h[ulGjo]hi":                 errBadAttr,
+	"%h[ul256]hi":                 errBadAttr,
+	"%h[u":                        errShort,
+	"%h[ul":                       errShort,
+	"%h[ulRed":                    errShort,
+}
+
+func TestUnderlineColors(t *testing.T) {
+	t.Parallel()
+	for k, v := range underlineColors {
+		if r := Highlight(k); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+}
+
+func TestDefaultColors(t *testing.T) {
+	t.Parallel()
+	for k, v := range defaultColors {
+		if r := Highlight(k); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+}
+
+func TestExtString(t *testing.T) {
+	t.Parallel()
+	if ti == nil {
+		t.Skip("no terminfo")
+	}
+	smulx, ok := ti.ExtStrings["Smulx"]
+	if !ok {
+		t.Skip("terminal lacks Smulx")
+	}
+	exp := strings.Replace(smulx, "%p1%d", "3", 1)
+	if r := defaultTerminal.extString(extModes["curlyunderline"]); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
 func TestColors(t *testing.T) {
 	t.Parallel()
 	for k, v := range colors {
-		exp := expF(ti.Color(v, -1)+"%s", "hi")
+		exp := expF(tiColor(v, -1)+"%s", "hi")
 		r := Highlight(fmt.Sprintf("%%h[fg%s]hi", k))
 		if r != exp {
 			t.Errorf("Expected %q but result was %q", exp, r)
 		}
-		exp = expF(ti.Color(-1, v)+"%s", "hi")
+		exp = expF(tiColor(-1, v)+"%s", "hi")
 		r = Highlight(fmt.Sprintf("%%h[bg%s]hi", k))
 		if r != exp {
 			t.Errorf("Expected %q but result was %q", exp, r)
@@ -51,12 +164,12 @@ func TestColors(t *testing.T) {
 func TestColors256(t *testing.T) {
 	t.Parallel()
 	for i := 0; i < 256; i++ {
-		exp := expF(ti.Color(i, -1)+"%s"+ti.Strings[caps.ExitAttributeMode], "hi")
+		exp := expF(tiColor(i, -1)+"%s"+ti.Strings[caps.ExitAttributeMode], "hi")
 		r := Highlight(fmt.Sprintf("%%h[fg%d]hi%%r", i))
 		if r != exp {
 			t.Errorf("Expected %q but result was %q", exp, r)
 		}
-		exp = expF(ti.Color(-1, i)+"%s"+ti.Strings[caps.ExitAttributeMode], "hi")
+		exp = expF(tiColor(-1, i)+"%s"+ti.Strings[caps.ExitAttributeMode], "hi")
 		r = Highlight(fmt.Sprintf("%%h[bg%d]hi%%r", i))
 		if r != exp {
 			t.Errorf("Expected %q but result was %q", exp, r)
@@ -64,9 +177,47 @@ func TestColors256(t *testing.T) {
 	}
 }
 
+func expRGB(fg bool, r, g, b uint8) string {
+	switch {
+	case ti == nil:
+		return ""
+	case trueColor && fg:
+		return rgbColor(targetFG, r, g, b)
+	case trueColor:
+		return rgbColor(targetBG, r, g, b)
+	case fg:
+		return tiColor(fitColor(r, g, b, maxColors), -1)
+	}
+	return tiColor(-1, fitColor(r, g, b, maxColors))
+}
+
+var colorsRGB = map[string]string{
+	"%h[fg#ff8800]hi":        expRGB(true, 255, 136, 0) + "hi",
+	"%h[bg#FF8800]hi":        expRGB(false, 255, 136, 0) + "hi",
+	"%h[fg#f80]hi":           expRGB(true, 255, 136, 0) + "hi",
+	"%h[bgrgb(12,34,56)]hi":  expRGB(false, 12, 34, 56) + "hi",
+	"%h[fgrgb(0, 0, 255)]hi": expRGB(true, 0, 0, 255) + "hi",
+	"%h[fg#000000+bold]hi":   expRGB(true, 0, 0, 0) + exp(ti.Strings[caps.EnterBoldMode]) + "hi",
+	"%h[fg#ff880]":           errBadAttr,
+	"%h[fg#gg8800]":          errBadAttr,
+	"%h[fgrgb(256,0,0)]":     errBadAttr,
+	"%h[fgrgb(1,2)]":         errBadAttr,
+	"%h[bgrgb(1,2,3]":        errBadAttr,
+	"%h[fg#ff8800":           errShort,
+}
+
+func TestColorsRGB(t *testing.T) {
+	t.Parallel()
+	for k, v := range colorsRGB {
+		if r := Highlight(k); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+}
+
 var combinations = map[string]string{
-	"%h[fgRed+bgBlue+bold+underline+fg23+bg235]hi":         expF(ti.Color(caps.Red, caps.Blue)+ti.Strings[caps.EnterBoldMode]+ti.Strings[caps.EnterUnderlineMode]+ti.Color(23, 235)+"%s", "hi"),
-	"%h[bgBlue+fgYellow+fgGreen+fg34+blink+dim+reverse]hi": expF(ti.Color(-1, caps.Blue)+ti.Color(caps.Yellow, -1)+ti.Color(caps.Green, -1)+ti.Color(34, -1)+ti.Strings[caps.EnterBlinkMode]+ti.Strings[caps.EnterDimMode]+ti.Strings[caps.EnterReverseMode]+"%s", "hi"),
+	"%h[fgRed+bgBlue+bold+underline+fg23+bg235]hi":         expF(tiColor(caps.Red, caps.Blue)+ti.Strings[caps.EnterBoldMode]+ti.Strings[caps.EnterUnderlineMode]+tiColor(23, 235)+"%s", "hi"),
+	"%h[bgBlue+fgYellow+fgGreen+fg34+blink+dim+reverse]hi": expF(tiColor(-1, caps.Blue)+tiColor(caps.Yellow, -1)+tiColor(caps.Green, -1)+tiColor(34, -1)+ti.Strings[caps.EnterBlinkMode]+ti.Strings[caps.EnterDimMode]+ti.Strings[caps.EnterReverseMode]+"%s", "hi"),
 }
 
 func TestCombinations(t *testing.T) {
@@ -79,7 +230,7 @@ func TestCombinations(t *testing.T) {
 }
 
 var highlightEdgeCases = map[string]string{
-	"%h[fgBrightBlack+%h[fgBlue]": exp(ti.Color(caps.BrightBlack, -1)) + errBadAttr,
+	"%h[fgBrightBlack+%h[fgBlue]": exp(tiColor(caps.BrightBlack, -1)) + errBadAttr,
 	"%h[":                   errShort,
 	"%h[f":                  errShort,
 	"%h[fg":                 errShort,
@@ -89,19 +240,20 @@ var highlightEdgeCases = map[string]string{
 	"%[bg232]":              "%[bg232]",
 	"%h[fg132":              errShort,
 	"%h[fgMagenta[]":        errBadAttr,
-	"%h[fgGreen+lold[]":     exp(ti.Color(caps.Green, -1)) + errBadAttr,
-	"%h[fgYellow+%#bgBlue]": exp(ti.Color(caps.Yellow, -1)) + errBadAttr,
+	"%h[fgGreen+lold[]":     exp(tiColor(caps.Green, -1)) + errBadAttr,
+	"%h[fgYellow+%#bgBlue]": exp(tiColor(caps.Yellow, -1)) + errBadAttr,
 	"%h][fgRed+%#bgBlue]":   errInvalid,
-	"%h[fgRed+":             exp(ti.Color(caps.Red, -1)) + errShort,
-	"%%h%h[fgRed]%%":        "%%h" + exp(ti.Color(caps.Red, -1)) + "%%",
+	"%h[fgRed+":             exp(tiColor(caps.Red, -1)) + errShort,
+	"%%h%h[fgRed]%%":        "%%h" + exp(tiColor(caps.Red, -1)) + "%%",
 	"%h[dsadadssadas]":      errBadAttr,
 	"%":                     "%",
 	"%h[fgsadas]":           errBadAttr,
-	"%h[fgCyan+%h[bgBlue]":  exp(ti.Color(caps.Cyan, -1)) + errBadAttr,
+	"%h[fgCyan+%h[bgBlue]":  exp(tiColor(caps.Cyan, -1)) + errBadAttr,
 	"lmaokai":               "lmaokai",
-	"%h[fgRed]%h[]":         exp(ti.Color(caps.Red, -1)) + errMissing,
+	"%h[fgRed]%h[]":         exp(tiColor(caps.Red, -1)) + errMissing,
 	"%h[bgGjo]%h[bgGreen]":  errBadAttr,
 	"%h[fg23a]":             errBadAttr,
+	"%h[fg256]":             errBadAttr,
 }
 
 func TestHighlightEdgeCases(t *testing.T) {
@@ -113,10 +265,108 @@ func TestHighlightEdgeCases(t *testing.T) {
 	}
 }
 
+var (
+	bold  = exp(ti.Strings[caps.EnterBoldMode])
+	reset = exp(ti.Strings[caps.ExitAttributeMode])
+	red   = exp(tiColor(caps.Red, -1))
+)
+
+var scopes = map[string]string{
+	"%h[bold]a%h[fgRed]b%h[/]c%h[/]d":      bold + "a" + red + "b" + reset + bold + "c" + reset + "d",
+	"%h[bold+fgRed]a%h[/]b":                bold + red + "a" + reset + "b",
+	"%h[bold]a%h[fgRed]b%r%h[/]c":          bold + "a" + red + "b" + reset + reset + "c",
+	"%h[bold]%s|%-20s|%[1]d|%%|%h[/]":      bold + "%s" + bold + "|%-20s" + bold + "|%[1]d" + bold + "|%%|" + reset,
+	"%h[bold]a%h[reset+fgRed]b%h[/]c%h[/]": bold + "a" + reset + red + "b" + reset + "c" + reset,
+	"%h[/]a%h[bold]%s":                     reset + "a" + bold + "%s" + bold,
+	"%h[bold]%s%h[/":                       bold + "%s" + errShort,
+	"%h[/+bold]":                           errBadAttr,
+	"%h[bold]%s%r":                         bold + "%s" + reset,
+	"%%h[/] %h[bold]%s":                    "%%h[/] " + bold + "%s",
+}
+
+func TestScopes(t *testing.T) {
+	t.Parallel()
+	for k, v := range scopes {
+		if r := Highlight(k); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+}
+
+const (
+	linkStart = "\x1b]8;;"
+	linkEnd   = "\x1b\\"
+)
+
+func expLink(url, text string) string {
+	if ti == nil {
+		return text
+	}
+	return linkStart + url + linkEnd + text + linkStart + linkEnd
+}
+
+var links = map[string]string{
+	"%l[https://example.com]text%L":  expLink("https://example.com", "text"),
+	"%#l[https://example.com]text%L": expLink("https://example.com", "text"),
+	"%l[http://a.b/%20c]%s%L":        expLink("http://a.b/%%20c", "%s"),
+	"%h[bold]%l[file:///a]a%L%r":     bold + expLink("file:///a", "a") + reset,
+	"%l[https://example.com]text":    exp(linkStart+"https://example.com"+linkEnd) + "text",
+	"%#v %#x %#":                     "%#v %#x %#",
+	"%l[]text%L":                     errMissing,
+	"%l[https://example.com":         errShort,
+	"%l(https://example.com)":        errInvalid,
+	"%l":                             errShort,
+	"%h[bold]%l[file:///a]%s%L%h[/]": bold + expLink("file:///a", "%s"+bold) + reset,
+}
+
+func TestLinks(t *testing.T) {
+	t.Parallel()
+	for k, v := range links {
+		if r := Highlight(k); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+}
+
+func TestRunLevel(t *testing.T) {
+	t.Parallel()
+	if ti == nil {
+		t.Skip("no terminfo")
+	}
+	const s = "%h[fg196+bg#ff8800]hi"
+	if r := RunLevel(s, None); r != "hi" {
+		t.Errorf("Expected %q but result was %q", "hi", r)
+	}
+	n := 16
+	if maxColors < n {
+		n = maxColors
+	}
+	exp := ti.Color(fitPaletteColor(196, n), -1) + ti.Color(-1, fitColor(255, 136, 0, n)) + "hi"
+	if r := RunLevel(s, Basic16); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = tiColor(196, -1) + tiColor(-1, fitColor(255, 136, 0, maxColors)) + "hi"
+	if r := RunLevel(s, Ansi256); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = tiColor(196, -1) + tiColor(-1, fitColor(255, 136, 0, maxColors)) + "hi"
+	if trueColor {
+		exp = tiColor(196, -1) + rgbColor(targetBG, 255, 136, 0) + "hi"
+	}
+	if r := RunLevel(s, TrueColor); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
 var stripEdgeCases = map[string]string{
-	"%h[fgRed]%smao%r": "%smao",
-	"%":                "%",
-	"%c":               "%c",
+	"%h[fgRed]%smao%r":               "%smao",
+	"%":                              "%",
+	"%c":                             "%c",
+	"%h[bold]a%h[/]%s":               "a%s",
+	"%l[https://example.com]text%L":  "text",
+	"%#l[https://example.com]text%L": "text (https://example.com)",
+	"%#l[http://a.b/%20c]%s%L":       "%s (http://a.b/%%20c)",
+	"%#l[https://example.com]text":   "text",
 }
 
 func TestStripEdgeCases(t *testing.T) {
@@ -0,0 +1,244 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nhooyr/color"
+)
+
+// Encoding is the format of the entries a Logger writes.
+type Encoding int
+
+// Encodings.
+const (
+	// Console entries are meant to be read by people. They consist of the header, the message
+	// and the fields, as described in the package documentation, and are colored unless
+	// color output is disabled.
+	Console Encoding = iota
+
+	// Logfmt entries are lines of key=value pairs: time, level, caller, prefix and msg, if the
+	// entry has them, followed by the fields, e.g.
+	//	time=2009-01-23T01:23:23Z level=info caller=main.go:12 msg="request done" status=200
+	Logfmt
+
+	// JSON entries are JSON objects with the same keys as Logfmt entries, one per line, e.g.
+	//	{"time":"2009-01-23T01:23:23Z","level":"info","caller":"main.go:12","msg":"request done","status":200}
+	JSON
+)
+
+// The structured encodings never contain control sequences, so that they do not leak into
+// log collectors. The highlight verbs in messages and prefixes are stripped and so are Format
+// arguments and field values. The time is included if the flags include Ldate, Ltime or
+// Lmicroseconds and the caller if they include Llongfile or Lshortfile.
+
+var encodingNames = [...]string{"Console", "Logfmt", "JSON"}
+
+func (e Encoding) String() string {
+	if e < Console || e > JSON {
+		return "Encoding(" + strconv.Itoa(int(e)) + ")"
+	}
+	return encodingNames[e]
+}
+
+// SetEncoding sets the encoding of the entries l writes. The default is Console.
+func (l *Logger) SetEncoding(e Encoding) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.encoding = e
+}
+
+// Encoding returns the encoding of the entries l writes.
+func (l *Logger) Encoding() Encoding {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.encoding
+}
+
+// encode appends e encoded with the encoding of c to b.
+func (c *config) encode(b []byte, e *entry) []byte {
+	switch c.encoding {
+	case Logfmt:
+		return c.appendLogfmt(b, e)
+	case JSON:
+		return c.appendJSON(b, e)
+	}
+	b = c.appendHeader(b, e)
+	b = append(b, e.msg...)
+	return c.appendFields(b, e)
+}
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+// timeLayout returns the layout of the time of the entries of the structured encodings.
+func (c *config) timeLayout() string {
+	if c.flags&Lmicroseconds != 0 {
+		return "2006-01-02T15:04:05.000000Z07:00"
+	}
+	return time.RFC3339
+}
+
+// plainPrefix returns the prefix of c without highlight verbs and surrounding spaces.
+func (c *config) plainPrefix() string {
+	return strings.TrimSpace(string(appendUnescaped(nil, c.prefixFormat.GetTerminal(c.term, color.None))))
+}
+
+// appendLogfmt appends e encoded as logfmt to b.
+func (c *config) appendLogfmt(b []byte, e *entry) []byte {
+	if !e.time.IsZero() {
+		b = c.appendField(b, "time", e.time.Format(c.timeLayout()))
+	}
+	if e.severity != noSeverity {
+		b = c.appendField(b, "level", levelNames[e.severity])
+	}
+	if e.file != "" {
+		b = c.appendField(b, "caller", e.caller())
+	}
+	if c.prefixFormat != nil {
+		b = c.appendField(b, "prefix", c.plainPrefix())
+	}
+	b = c.appendField(b, "msg", e.msg)
+	return c.appendFields(b, e)
+}
+
+// appendJSON appends e encoded as a JSON object to b.
+func (c *config) appendJSON(b []byte, e *entry) []byte {
+	b = append(b, '{')
+	if !e.time.IsZero() {
+		b = appendJSONField(b, "time", e.time.Format(c.timeLayout()))
+	}
+	if e.severity != noSeverity {
+		b = appendJSONField(b, "level", levelNames[e.severity])
+	}
+	if e.file != "" {
+		b = appendJSONField(b, "caller", e.caller())
+	}
+	if c.prefixFormat != nil {
+		b = appendJSONField(b, "prefix", c.plainPrefix())
+	}
+	b = appendJSONField(b, "msg", e.msg)
+	if e.severity != noSeverity {
+		for _, f := range c.fields {
+			b = appendJSONField(b, f.key, c.plainValue(f.value))
+		}
+		for i := 0; i < len(e.kv); i += 2 {
+			key, value := pair(e.kv, i)
+			b = appendJSONField(b, key, c.plainValue(value))
+		}
+	}
+	return append(b, '}')
+}
+
+// plainValue returns value, or its string without control sequences if it is a Format.
+func (c *config) plainValue(value interface{}) interface{} {
+	if f, ok := value.(*color.Format); ok {
+		return f.GetTerminal(c.term, color.None)
+	}
+	return value
+}
+
+// appendJSONField appends the member "key":value to the JSON object in b.
+func appendJSONField(b []byte, key string, value interface{}) []byte {
+	if b[len(b)-1] != '{' {
+		b = append(b, ',')
+	}
+	b = appendJSONString(b, key)
+	b = append(b, ':')
+	return appendJSONValue(b, value)
+}
+
+// appendJSONValue appends value encoded as JSON to b. Errors and fmt.Stringers are encoded
+// as their strings, and values that cannot be encoded as the string fmt.Sprint returns.
+func appendJSONValue(b []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, v)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case float32:
+		return appendJSONFloat(b, float64(v), 32)
+	case float64:
+		return appendJSONFloat(b, v, 64)
+	case error:
+		return appendJSONString(b, v.Error())
+	case json.Marshaler:
+		// Encoded by json.Marshal below, even if it is also a fmt.Stringer.
+	case fmt.Stringer:
+		return appendJSONString(b, v.String())
+	}
+	p, err := json.Marshal(value)
+	if err != nil {
+		return appendJSONString(b, fmt.Sprint(value))
+	}
+	return append(b, p...)
+}
+
+// appendJSONFloat appends f to b, as a string if it is not a finite number.
+func appendJSONFloat(b []byte, f float64, bitSize int) []byte {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return appendJSONString(b, strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, bitSize)
+}
+
+// appendJSONString appends s encoded as a JSON string to b. Control characters, including
+// those of control sequences, are escaped and invalid UTF-8 is replaced with U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, `\n`...)
+			case c == '\r':
+				b = append(b, `\r`...)
+			case c == '\t':
+				b = append(b, `\t`...)
+			case c < ' ' || c == 0x7f:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, `\ufffd`...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
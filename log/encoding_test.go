@@ -0,0 +1,153 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nhooyr/color"
+)
+
+func TestLogfmt(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.TrueColor)
+	l.SetEncoding(Logfmt)
+	if r := l.Encoding(); r != Logfmt {
+		t.Errorf("Expected %v but result was %v", Logfmt, r)
+	}
+	l.SetPrefix("%h[fgMagenta]api:%r ")
+	f := color.Prepare("%h[bold]two words")
+	l.With("user", f).Infof("%h[fgRed]request %s%r", f)
+	l.Warnw("slow", "ms", 300, "path", "/a=b")
+	l.Print("no level\n")
+	exp := `level=info prefix=api: msg="request two words" user="two words"
+level=warn prefix=api: msg=slow ms=300 path="/a=b"
+prefix=api: msg="no level"
+`
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestPrefixEncodings(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	l.SetPrefix("100%% ")
+	l.SetEncoding(Logfmt)
+	l.Print("a")
+	exp := "prefix=100% msg=a\n"
+	l.SetEncoding(JSON)
+	l.Print("a")
+	exp += `{"prefix":"100%","msg":"a"}` + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestLogfmtHeader(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	l.SetEncoding(Logfmt)
+	l.SetFlags(LstdFlags | Lmicroseconds | LUTC | Lshortfile)
+	l.Error("failed")
+	exp := `^time=\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z level=error caller=encoding_test\.go:\d+ msg=failed\n$`
+	if !regexp.MustCompile(exp).MatchString(b.String()) {
+		t.Errorf("Expected a match for %q but result was %q", exp, b.String())
+	}
+}
+
+type stringer struct{}
+
+func (stringer) String() string {
+	return "stringer"
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.TrueColor)
+	l.SetEncoding(JSON)
+	l.SetFlags(Ltime | LUTC | Lshortfile)
+	l.With("f", color.Prepare("%h[fgRed]red")).Errorf("%h[bold]failed%r: %v", "\x1b[31mEOF\x1b[m")
+	l.Print("no level")
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&b)
+	for dec.More() {
+		var e map[string]interface{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries but result was %d", len(entries))
+	}
+	tests := []map[string]string{
+		{"level": "error", "msg": "failed: \x1b[31mEOF\x1b[m", "f": "red"},
+		{"msg": "no level"},
+	}
+	for i, exp := range tests {
+		e := entries[i]
+		for k, v := range exp {
+			if e[k] != v {
+				t.Errorf("Expected %q but result was %q", v, e[k])
+			}
+		}
+		if _, err := time.Parse(time.RFC3339, e["time"].(string)); err != nil {
+			t.Error(err)
+		}
+		if e["caller"] == nil {
+			t.Errorf("Expected a caller in %v", e)
+		}
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value interface{}
+		exp   string
+	}{
+		{nil, `null`},
+		{"a\"\\\n\x1b\xff日", `"a\"\\\n\u001b\ufffd日"`},
+		{true, `true`},
+		{-3, `-3`},
+		{uint8(3), `3`},
+		{1.5, `1.5`},
+		{math.Inf(1), `"+Inf"`},
+		{errors.New("EOF"), `"EOF"`},
+		{stringer{}, `"stringer"`},
+		{time.Duration(0), `"0s"`},
+		{[]int{1, 2}, `[1,2]`},
+		{map[string]int{"a": 1}, `{"a":1}`},
+		{complex(1, 2), `"(1+2i)"`},
+	}
+	for _, tt := range tests {
+		r := string(appendJSONValue(nil, tt.value))
+		if r != tt.exp {
+			t.Errorf("Expected %q but result was %q", tt.exp, r)
+		}
+	}
+}
+
+func TestEncodingString(t *testing.T) {
+	t.Parallel()
+	tests := map[Encoding]string{
+		Console:     "Console",
+		Logfmt:      "Logfmt",
+		JSON:        "JSON",
+		Encoding(9): "Encoding(9)",
+	}
+	for e, exp := range tests {
+		if r := e.String(); r != exp {
+			t.Errorf("Expected %q but result was %q", exp, r)
+		}
+	}
+}
@@ -8,7 +8,7 @@ import (
 func Example() {
 	redFormat := color.Prepare("%h[fgRed]%s%r\n")
 
-	// If os.Stderr is a terminal, this will print in color.
+	// If color.Detect reports that os.Stderr supports color, this will print in color.
 	// Otherwise it will be a normal "foo".
 	log.Printfp(redFormat, "foo")
 
@@ -16,6 +16,15 @@ func Example() {
 	log.SetColor(false)
 	log.Printfp(redFormat, "bar")
 
+	// "INFO  baz" without a highlighted tag. "DEBUG qux" is discarded as debug messages
+	// are only logged after log.SetLevel(log.SeverityDebug).
+	log.Info("baz")
+	log.Debug("qux")
+
+	// "INFO  request user=bob path=/ status=200" with the keys and values highlighted
+	// if color is enabled.
+	log.With("user", "bob").Infow("request", "path", "/", "status", 200)
+
 	// "foo" with a red foreground.
 	log.SetColor(true)
 	log.Fatalfp(redFormat, "foo")
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nhooyr/color"
+)
+
+// field is a key/value pair logged after the messages of a Logger.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// missing is the value of a key without one.
+const missing = "%!v(MISSING)"
+
+// pair returns the key and value at index i of the alternating keys and values in kv.
+func pair(kv []interface{}, i int) (string, interface{}) {
+	key, ok := kv[i].(string)
+	if !ok {
+		key = fmt.Sprint(kv[i])
+	}
+	if i+1 >= len(kv) {
+		return key, missing
+	}
+	return key, kv[i+1]
+}
+
+var (
+	defaultKeyStyle   = newStyle("fgBlue")
+	defaultValueStyle = newStyle("fgGreen")
+	resetFormat       = color.Prepare("%r")
+)
+
+// newStyle returns a Format that sets the attributes attrs, or nil if attrs is empty.
+func newStyle(attrs string) *color.Format {
+	if attrs == "" {
+		return nil
+	}
+	return color.Prepare("%h[" + attrs + "]")
+}
+
+// With returns a child of l that logs the fields made of the alternating keys and values
+// in kv after each of its messages, following the fields of l. Keys that are not strings
+// are converted with fmt.Sprint.
+//
+// The fields are written like logfmt, as key=value separated by spaces, and are only
+// logged with the messages of the Debug, Info, Warn and Error families. The child writes
+// to the same output as l and starts with a copy of its other settings.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	child := &Logger{out: l.out, config: l.config}
+	child.fields = make([]field, len(l.fields), len(l.fields)+(len(kv)+1)/2)
+	copy(child.fields, l.fields)
+	for i := 0; i < len(kv); i += 2 {
+		key, value := pair(kv, i)
+		child.fields = append(child.fields, field{key, value})
+	}
+	return child
+}
+
+// SetFieldStyle sets the attributes of the keys and values of fields, e.g. "fgBlue" and
+// "bold". An empty string applies none. The defaults are fgBlue keys and fgGreen values.
+func (l *Logger) SetFieldStyle(key, value string) {
+	keyStyle, valueStyle := newStyle(key), newStyle(value)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keyStyle, l.valueStyle = keyStyle, valueStyle
+}
+
+// appendFields appends the fields of c followed by those of e to b, if e has a severity.
+func (c *config) appendFields(b []byte, e *entry) []byte {
+	if e.severity == noSeverity {
+		return b
+	}
+	for _, f := range c.fields {
+		b = c.appendField(b, f.key, f.value)
+	}
+	for i := 0; i < len(e.kv); i += 2 {
+		key, value := pair(e.kv, i)
+		b = c.appendField(b, key, value)
+	}
+	return b
+}
+
+// appendField appends the field key=value to b, preceded by a space unless b is empty.
+func (c *config) appendField(b []byte, key string, value interface{}) []byte {
+	if len(b) > 0 {
+		b = append(b, ' ')
+	}
+	b = c.appendStyled(b, c.keyStyle, key, key)
+	b = append(b, '=')
+	if f, ok := value.(*color.Format); ok {
+		// The control sequences of f do not affect whether the value is quoted.
+		plain := f.GetTerminal(c.term, color.None)
+		return c.appendStyled(b, c.valueStyle, f.GetTerminal(c.term, c.colorLevel), plain)
+	}
+	s, ok := value.(string)
+	if !ok {
+		s = fmt.Sprint(value)
+	}
+	return c.appendStyled(b, c.valueStyle, s, s)
+}
+
+// appendStyled appends s highlighted with style to b, quoted if plain, the text of s
+// without control sequences, contains anything that would break the logfmt syntax.
+func (c *config) appendStyled(b []byte, style *color.Format, s, plain string) []byte {
+	if style != nil {
+		b = append(b, style.GetTerminal(c.term, c.colorLevel)...)
+	}
+	switch {
+	case !needsQuote(plain):
+		b = append(b, s...)
+	case s == plain:
+		b = strconv.AppendQuote(b, s)
+	default:
+		b = append(b, '"')
+		b = append(b, quoter.Replace(s)...)
+		b = append(b, '"')
+	}
+	if style != nil {
+		b = append(b, resetFormat.GetTerminal(c.term, c.colorLevel)...)
+	}
+	return b
+}
+
+// quoter quotes highlighted strings without escaping their control sequences.
+var quoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// needsQuote returns true if s is empty or contains spaces, control characters,
+// invalid UTF-8, '=' or '"'.
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
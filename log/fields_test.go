@@ -0,0 +1,79 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nhooyr/color"
+)
+
+func TestWith(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	child := l.With("user", "bob", "id", 3)
+	child.With("req", 9).Infow("request", "path", "/a b")
+	child.Warnf("slow %dms", 300)
+	child.Error("failed\n")
+	l.Info("parent")
+	exp := `INFO  request user=bob id=3 req=9 path="/a b"
+WARN  slow 300ms user=bob id=3
+ERROR failed user=bob id=3
+INFO  parent
+`
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestFieldValues(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		kv  []interface{}
+		exp string
+	}{
+		{[]interface{}{"a", ""}, `a=""`},
+		{[]interface{}{"a", "x=y"}, `a="x=y"`},
+		{[]interface{}{"a", `say "hi"`}, `a="say \"hi\""`},
+		{[]interface{}{"a", "two\nlines"}, `a="two\nlines"`},
+		{[]interface{}{"a", errors.New("EOF")}, `a=EOF`},
+		{[]interface{}{"a", 1.5, "b", nil}, `a=1.5 b=<nil>`},
+		{[]interface{}{"a", color.Prepare("%h[fgRed]red")}, `a=red`},
+		{[]interface{}{"a", color.Prepare("%h[fgRed]two words")}, `a="two words"`},
+		{[]interface{}{1, "b"}, `1=b`},
+		{[]interface{}{"the key", "b"}, `"the key"=b`},
+		{[]interface{}{"a"}, `a=%!v(MISSING)`},
+	}
+	var b bytes.Buffer
+	l := New(&b, false)
+	for _, tt := range tests {
+		b.Reset()
+		l.Infow("m", tt.kv...)
+		exp := "INFO  m " + tt.exp + "\n"
+		if b.String() != exp {
+			t.Errorf("Expected %q but result was %q", exp, b.String())
+		}
+	}
+}
+
+func TestFieldStyle(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.Ansi256)
+	l.SetLevelStyle(SeverityInfo, "")
+	f := color.Prepare("%h[bold]a b")
+	l.Infow("m", "k", 1, "f", f)
+	exp := color.RunLevel(`INFO  m %h[fgBlue]k%r=%h[fgGreen]1%r %h[fgBlue]f%r=%h[fgGreen]"`, color.Ansi256) +
+		f.GetLevel(color.Ansi256) + color.RunLevel(`"%r`, color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	l.SetFieldStyle("fgRed", "")
+	l.Infow("m", "k", 1)
+	exp = color.RunLevel("INFO  m %h[fgRed]k%r=1", color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
@@ -0,0 +1,163 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/nhooyr/color"
+)
+
+// These flags define the header written before each message, in the same way as in the
+// log package of the standard library:
+//
+//	2009/01/23 01:23:23.123123 /a/b/c/d.go:23: message
+//
+// Unlike there, no flags are set by default.
+const (
+	Ldate         = 1 << iota     // the date in the local time zone: 2009/01/23
+	Ltime                         // the time in the local time zone: 01:23:23
+	Lmicroseconds                 // microsecond resolution: 01:23:23.123123. assumes Ltime.
+	Llongfile                     // full file name and line number: /a/b/c/d.go:23
+	Lshortfile                    // final file name element and line number: d.go:23. overrides Llongfile
+	LUTC                          // if Ldate or Ltime is set, use UTC rather than the local time zone
+	Lmsgprefix                    // move the prefix from the beginning of the line to before the message
+	LstdFlags     = Ldate | Ltime // initial values for the standard logger in the standard library
+)
+
+// callDepth is the number of frames between output and the caller of an exported function
+// or method: output, the helper that called it and the exported function or method.
+const callDepth = 3
+
+// Indexes of the styles of the components of the header.
+const (
+	dateStyle = iota
+	timeStyle
+	fileStyle
+)
+
+var defaultFlagStyles = [...]*color.Format{
+	dateStyle: newStyle("dim"),
+	timeStyle: newStyle("dim"),
+	fileStyle: newStyle("fgCyan"),
+}
+
+// SetFlags sets the flags that define the header written before each message.
+func (l *Logger) SetFlags(flag int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flags = flag
+}
+
+// Flags returns the flags that define the header written before each message.
+func (l *Logger) Flags() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flags
+}
+
+// SetPrefix sets the prefix written at the beginning of each line, or before each message
+// if the Lmsgprefix flag is set. The highlight verbs in prefix are processed, so it can be
+// highlighted like "%h[fgMagenta]api:%r ", and %% is written as a single %.
+func (l *Logger) SetPrefix(prefix string) {
+	var f *color.Format
+	if prefix != "" {
+		f = color.Prepare(prefix)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prefix, l.prefixFormat = prefix, f
+}
+
+// Prefix returns the prefix of l as passed to SetPrefix.
+func (l *Logger) Prefix() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.prefix
+}
+
+// SetFlagStyle sets the attributes of the component of the header selected by flag, which
+// is one of Ldate, Ltime, Lmicroseconds, Llongfile or Lshortfile, e.g. "fgCyan". Ltime and
+// Lmicroseconds select the time and Llongfile and Lshortfile the file name and line number.
+// An empty string applies none. By default the date and time are dim and the file name and
+// line number are cyan.
+func (l *Logger) SetFlagStyle(flag int, attrs string) {
+	var i int
+	switch flag {
+	case Ldate:
+		i = dateStyle
+	case Ltime, Lmicroseconds:
+		i = timeStyle
+	case Llongfile, Lshortfile:
+		i = fileStyle
+	default:
+		return
+	}
+	style := newStyle(attrs)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flagStyles[i] = style
+}
+
+// appendHeader appends what is written before the message of e to b: the prefix, the
+// date, time and caller selected by the flags of c and the tag of the severity of e.
+func (c *config) appendHeader(b []byte, e *entry) []byte {
+	if c.flags&Lmsgprefix == 0 {
+		b = c.appendPrefix(b)
+	}
+	if !e.time.IsZero() {
+		if c.flags&Ldate != 0 {
+			b = c.appendHighlighted(b, c.flagStyles[dateStyle], e.time.Format("2006/01/02"))
+			b = append(b, ' ')
+		}
+		if c.flags&(Ltime|Lmicroseconds) != 0 {
+			layout := "15:04:05"
+			if c.flags&Lmicroseconds != 0 {
+				layout = "15:04:05.000000"
+			}
+			b = c.appendHighlighted(b, c.flagStyles[timeStyle], e.time.Format(layout))
+			b = append(b, ' ')
+		}
+	}
+	if e.file != "" {
+		b = c.appendHighlighted(b, c.flagStyles[fileStyle], e.caller())
+		b = append(b, ": "...)
+	}
+	if e.severity != noSeverity {
+		b = append(b, c.tags[e.severity].GetTerminal(c.term, c.colorLevel)...)
+		b = append(b, ' ')
+	}
+	if c.flags&Lmsgprefix != 0 {
+		b = c.appendPrefix(b)
+	}
+	return b
+}
+
+// appendPrefix appends the prefix of c to b.
+func (c *config) appendPrefix(b []byte) []byte {
+	if c.prefixFormat == nil {
+		return b
+	}
+	return appendUnescaped(b, c.prefixFormat.GetTerminal(c.term, c.colorLevel))
+}
+
+// appendUnescaped appends s to b with each %% replaced by a single %. The strings of a
+// Format are format strings, but the prefix is written without being formatted.
+func appendUnescaped(b []byte, s string) []byte {
+	for {
+		i := strings.Index(s, "%%")
+		if i < 0 {
+			return append(b, s...)
+		}
+		b = append(b, s[:i+1]...)
+		s = s[i+2:]
+	}
+}
+
+// appendHighlighted appends s highlighted with style to b.
+func (c *config) appendHighlighted(b []byte, style *color.Format, s string) []byte {
+	if style == nil {
+		return append(b, s...)
+	}
+	b = append(b, style.GetTerminal(c.term, c.colorLevel)...)
+	b = append(b, s...)
+	return append(b, resetFormat.GetTerminal(c.term, c.colorLevel)...)
+}
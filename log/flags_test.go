@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/nhooyr/color"
+)
+
+// callerLine returns the number of the line that called it plus offset.
+func callerLine(offset int) string {
+	_, _, line, _ := runtime.Caller(1)
+	return strconv.Itoa(line + offset)
+}
+
+func TestShortfile(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, false)
+	l.SetFlags(Lshortfile)
+	var exp string
+	l.Print("a")
+	exp += "flags_test.go:" + callerLine(-1) + ": a\n"
+	l.Printf("%s", "b")
+	exp += "flags_test.go:" + callerLine(-1) + ": b\n"
+	l.Infow("c", "k", 1)
+	exp += "flags_test.go:" + callerLine(-1) + ": INFO  c k=1\n"
+	l.With("k", 2).Errorfp(color.Prepare("%s"), "d")
+	exp += "flags_test.go:" + callerLine(-1) + ": ERROR d k=2\n"
+	func() {
+		defer func() { recover() }()
+		exp += "flags_test.go:" + callerLine(1) + ": e\n"
+		l.Panicln("e")
+	}()
+
+	defer SetOutput(std.out.w)
+	defer SetFlags(Flags())
+	SetOutput(&b)
+	SetFlags(Lshortfile)
+	Println("f")
+	exp += "flags_test.go:" + callerLine(-1) + ": f\n"
+	Warn("g")
+	exp += "flags_test.go:" + callerLine(-1) + ": WARN  g\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestFlags(t *testing.T) {
+	t.Parallel()
+	tests := map[int]string{
+		0:                            `^foo\n$`,
+		Ldate:                        `^\d{4}/\d\d/\d\d foo\n$`,
+		Ltime:                        `^\d\d:\d\d:\d\d foo\n$`,
+		Ltime | Lmicroseconds | LUTC: `^\d\d:\d\d:\d\d\.\d{6} foo\n$`,
+		LstdFlags:                    `^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d foo\n$`,
+		Llongfile:                    `^/.*/log/flags_test\.go:\d+: foo\n$`,
+		Lshortfile | Llongfile:       `^flags_test\.go:\d+: foo\n$`,
+	}
+	var b bytes.Buffer
+	l := New(&b, false)
+	for flag, exp := range tests {
+		b.Reset()
+		l.SetFlags(flag)
+		if r := l.Flags(); r != flag {
+			t.Errorf("Expected %d but result was %d", flag, r)
+		}
+		l.Print("foo")
+		if !regexp.MustCompile(exp).MatchString(b.String()) {
+			t.Errorf("Expected a match for %q but result was %q", exp, b.String())
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	l.SetPrefix("%h[fgMagenta]api:%r ")
+	if r := l.Prefix(); r != "%h[fgMagenta]api:%r " {
+		t.Errorf("Expected %q but result was %q", "%h[fgMagenta]api:%r ", r)
+	}
+	l.SetFlags(Lshortfile)
+	l.Print("a")
+	exp := "api: flags_test.go:" + callerLine(-1) + ": a\n"
+	l.Info("b")
+	exp += "api: flags_test.go:" + callerLine(-1) + ": INFO  b\n"
+	l.SetFlags(Lshortfile | Lmsgprefix)
+	l.Info("c")
+	exp += "flags_test.go:" + callerLine(-1) + ": INFO  api: c\n"
+	l.SetFlags(0)
+	l.SetPrefix("100%% %h[bold]%%d:%r ")
+	l.Print("d")
+	exp += "100% %d: d\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestFlagStyle(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.Ansi256)
+	l.SetFlags(Lshortfile)
+	l.SetPrefix("%h[fgMagenta]api:%r ")
+	l.Print("a")
+	exp := color.RunLevel("%h[fgMagenta]api:%r %h[fgCyan]flags_test.go:"+callerLine(-1)+"%r: a", color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	l.SetFlagStyle(Lshortfile, "bold")
+	l.SetFlagStyle(LUTC, "fgRed")
+	l.SetPrefix("")
+	l.Print("a")
+	exp = color.RunLevel("%h[bold]flags_test.go:"+callerLine(-1)+"%r: a", color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
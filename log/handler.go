@@ -0,0 +1,155 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/nhooyr/color"
+)
+
+// Handler is a slog.Handler that writes records with a Logger. The level of each record
+// is written as the tag of the closest Severity, the attributes are written as fields with
+// the names of their groups joined with dots, e.g. req.method=GET, and everything else is
+// written according to the settings of the Logger, including its color support, flags,
+// prefix and encoding. The time of the record is written if the flags include Ldate, Ltime
+// or Lmicroseconds, and the caller if they include Llongfile or Lshortfile.
+type Handler struct {
+	l      *Logger
+	opts   slog.HandlerOptions
+	groups []string      // groups opened with WithGroup
+	attrs  []interface{} // alternating keys and values of the attributes added with WithAttrs
+}
+
+// NewHandler returns a Handler that writes to w with a new Logger, with color output
+// enabled if w is a file that color.Detect reports supports it. See NewLoggerHandler
+// for opts.
+func NewHandler(w io.Writer, opts *slog.HandlerOptions) *Handler {
+	level := color.None
+	if f, ok := w.(*os.File); ok {
+		level = color.Detect(f)
+	}
+	return NewLoggerHandler(NewColorLevel(w, level), opts)
+}
+
+// NewLoggerHandler returns a Handler that writes with l. If opts is nil, the default
+// options are used. If opts.Level is nil, the records of a lower severity than the one
+// set with l.SetLevel are discarded. If opts.AddSource is true, the caller is written
+// as if the flags of l include Llongfile. opts.ReplaceAttr is called for the attributes
+// of the records but not for their time, level, message and source.
+func NewLoggerHandler(l *Logger, opts *slog.HandlerOptions) *Handler {
+	h := &Handler{l: l}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Logger returns the Logger h writes with.
+func (h *Handler) Logger() *Logger {
+	return h.l
+}
+
+// severityOf returns the Severity closest to the slog level l.
+func severityOf(l slog.Level) Severity {
+	switch {
+	case l < slog.LevelInfo:
+		return SeverityDebug
+	case l < slog.LevelWarn:
+		return SeverityInfo
+	case l < slog.LevelError:
+		return SeverityWarn
+	}
+	return SeverityError
+}
+
+// Enabled reports whether h handles records of the level l.
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	if h.opts.Level != nil {
+		return l >= h.opts.Level.Level()
+	}
+	return severityOf(l) >= h.l.Level()
+}
+
+// Handle writes r.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	c, _ := h.l.load(noSeverity)
+	e := entry{severity: severityOf(r.Level), msg: r.Message}
+	if c.flags&(Ldate|Ltime|Lmicroseconds) != 0 {
+		e.time = r.Time
+		if c.flags&LUTC != 0 {
+			e.time = e.time.UTC()
+		}
+	}
+	if (c.flags&(Llongfile|Lshortfile) != 0 || h.opts.AddSource) && r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.file, e.line = f.File, f.Line
+		if c.flags&Lshortfile != 0 {
+			e.file = e.file[strings.LastIndexByte(e.file, '/')+1:]
+		}
+	}
+	kv := make([]interface{}, len(h.attrs), len(h.attrs)+2*r.NumAttrs())
+	copy(kv, h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		kv = h.appendAttr(kv, h.groups, a)
+		return true
+	})
+	e.kv = kv
+	return h.l.write(&c, &e)
+}
+
+// WithAttrs returns a Handler that also writes attrs with each record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	for _, a := range attrs {
+		h2.attrs = h.appendAttr(h2.attrs, h.groups, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a Handler that qualifies the keys of the attributes added later with name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+// appendAttr appends the key of a, qualified with the names of groups, and its value to kv.
+// The attributes of groups are appended individually.
+func (h *Handler) appendAttr(kv []interface{}, groups []string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, a := range attrs {
+			kv = h.appendAttr(kv, groups, a)
+		}
+		return kv
+	}
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return kv
+	}
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+	return append(kv, key, a.Value.Any())
+}
@@ -0,0 +1,93 @@
+//go:build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nhooyr/color"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	sl := slog.New(NewLoggerHandler(l, nil))
+	sl.Debug("discarded")
+	sl.Info("request", "method", "GET", slog.Group("resp", "status", 200, slog.Group("", "ms", 3)))
+	sl.With("user", "bob").WithGroup("db").With("table", "users").Warn("slow", "rows", 2, slog.Group("empty"))
+	sl.Log(context.Background(), slog.LevelError+4, "failed", "err", color.Prepare("%h[fgRed]no route"))
+	exp := `INFO  request method=GET resp.status=200 resp.ms=3
+WARN  slow user=bob db.table=users db.rows=2
+ERROR failed err="no route"
+`
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestHandlerColor(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.Ansi256)
+	h := NewLoggerHandler(l, nil)
+	slog.New(h).Warn("slow", "ms", 3)
+	exp := color.RunLevel("%h[fgYellow+bold]WARN%r  slow %h[fgBlue]ms%r=%h[fgGreen]3%r", color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	h.Logger().SetColor(false)
+	slog.New(h).Warn("slow", "ms", 3)
+	exp = "WARN  slow ms=3\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestHandlerOptions(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	opts := &slog.HandlerOptions{
+		Level:     slog.LevelDebug,
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "secret" {
+				return slog.Attr{}
+			}
+			if len(groups) > 0 && a.Key == "d" {
+				a.Value = slog.StringValue(a.Value.Duration().String())
+			}
+			return a
+		},
+	}
+	h := NewHandler(&b, opts)
+	h.Logger().SetEncoding(JSON)
+	h.Logger().SetFlags(Lshortfile)
+	slog.New(h).WithGroup("g").Debug("hi", "secret", 1, "d", time.Second)
+	exp := `^\{"level":"debug","caller":"handler_test\.go:\d+","msg":"hi","g\.d":"1s"\}\n$`
+	if !regexp.MustCompile(exp).MatchString(b.String()) {
+		t.Errorf("Expected a match for %q but result was %q", exp, b.String())
+	}
+}
+
+func TestHandlerTime(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	l.SetFlags(LstdFlags | LUTC)
+	h := NewLoggerHandler(l, nil)
+	r := slog.NewRecord(time.Date(2009, 1, 23, 1, 23, 23, 0, time.UTC), slog.LevelInfo, "hi", 0)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	exp := "2009/01/23 01:23:23 INFO  hi\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"strconv"
+
+	"github.com/nhooyr/color"
+)
+
+// Severity is the severity of a log message.
+type Severity int
+
+// Severities in increasing order.
+const (
+	SeverityDebug Severity = iota
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+)
+
+// noSeverity is the severity of the messages of the Print, Fatal and Panic families.
+// They are never discarded and have no tag.
+const noSeverity Severity = -1
+
+var severityNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (s Severity) String() string {
+	if s < SeverityDebug || s > SeverityError {
+		return "Severity(" + strconv.Itoa(int(s)) + ")"
+	}
+	return severityNames[s]
+}
+
+// defaultStyles are the attributes of the tag of each severity.
+var defaultStyles = [...]string{"fgBrightBlack", "fgCyan", "fgYellow+bold", "fgRed+bold"}
+
+var defaultTags = func() (tags [SeverityError + 1]*color.Format) {
+	for s := range tags {
+		tags[s] = newTag(Severity(s), defaultStyles[s])
+	}
+	return tags
+}()
+
+// newTag returns the tag written before the messages of severity s, with the name
+// of s highlighted with attrs and padded so that the messages line up.
+func newTag(s Severity, attrs string) *color.Format {
+	name := s.String()
+	pad := color.PadRight("", len("ERROR")-len(name))
+	if attrs == "" {
+		return color.Prepare(name + pad)
+	}
+	return color.Prepare("%h[" + attrs + "]" + name + "%r" + pad)
+}
+
+// SetLevel sets the minimum severity of the messages l logs. Messages logged with a
+// lower severity are discarded. The default is SeverityInfo.
+// It does not affect the Print, Fatal and Panic families.
+func (l *Logger) SetLevel(s Severity) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = s
+}
+
+// Level returns the minimum severity of the messages l logs.
+func (l *Logger) Level() Severity {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// SetLevelStyle sets the attributes of the tag written before the messages of severity s,
+// e.g. "fgMagenta+bold". An empty string applies none. If attrs is invalid, the tag contains
+// the error like any other highlight verb.
+func (l *Logger) SetLevelStyle(s Severity, attrs string) {
+	if s < SeverityDebug || s > SeverityError {
+		return
+	}
+	tag := newTag(s, attrs)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tags[s] = tag
+}
+
+// Debugf logs a debug message like l.Printf.
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.logf(SeverityDebug, format, v)
+}
+
+// Debugfp is the same as l.Debugf but takes a prepared format struct.
+func (l *Logger) Debugfp(f *color.Format, v ...interface{}) {
+	l.logfp(SeverityDebug, f, v)
+}
+
+// Debug logs a debug message like l.Print.
+func (l *Logger) Debug(v ...interface{}) {
+	l.log(SeverityDebug, v)
+}
+
+// Debugw logs a debug message followed by the fields of l and the alternating keys
+// and values in kv.
+func (l *Logger) Debugw(msg string, kv ...interface{}) {
+	l.logw(SeverityDebug, msg, kv)
+}
+
+// Infof logs an info message like l.Printf.
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.logf(SeverityInfo, format, v)
+}
+
+// Infofp is the same as l.Infof but takes a prepared format struct.
+func (l *Logger) Infofp(f *color.Format, v ...interface{}) {
+	l.logfp(SeverityInfo, f, v)
+}
+
+// Info logs an info message like l.Print.
+func (l *Logger) Info(v ...interface{}) {
+	l.log(SeverityInfo, v)
+}
+
+// Infow logs an info message followed by the fields of l and the alternating keys
+// and values in kv.
+func (l *Logger) Infow(msg string, kv ...interface{}) {
+	l.logw(SeverityInfo, msg, kv)
+}
+
+// Warnf logs a warning message like l.Printf.
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.logf(SeverityWarn, format, v)
+}
+
+// Warnfp is the same as l.Warnf but takes a prepared format struct.
+func (l *Logger) Warnfp(f *color.Format, v ...interface{}) {
+	l.logfp(SeverityWarn, f, v)
+}
+
+// Warn logs a warning message like l.Print.
+func (l *Logger) Warn(v ...interface{}) {
+	l.log(SeverityWarn, v)
+}
+
+// Warnw logs a warning message followed by the fields of l and the alternating keys
+// and values in kv.
+func (l *Logger) Warnw(msg string, kv ...interface{}) {
+	l.logw(SeverityWarn, msg, kv)
+}
+
+// Errorf logs an error message like l.Printf.
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.logf(SeverityError, format, v)
+}
+
+// Errorfp is the same as l.Errorf but takes a prepared format struct.
+func (l *Logger) Errorfp(f *color.Format, v ...interface{}) {
+	l.logfp(SeverityError, f, v)
+}
+
+// Error logs an error message like l.Print.
+func (l *Logger) Error(v ...interface{}) {
+	l.log(SeverityError, v)
+}
+
+// Errorw logs an error message followed by the fields of l and the alternating keys
+// and values in kv.
+func (l *Logger) Errorw(msg string, kv ...interface{}) {
+	l.logw(SeverityError, msg, kv)
+}
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nhooyr/color"
+)
+
+func TestSeverities(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	l.SetLevel(SeverityDebug)
+	f := color.Prepare("%h[bold]%s")
+	l.Debug("a", 1)
+	l.Infof("%h[fgRed]%s", "b")
+	l.Warnfp(f, "c")
+	l.Error(color.Prepare("%h[bold]d"), "e")
+	exp := "DEBUG a1\nINFO  b\nWARN  c\nERROR de\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	if r := l.Level(); r != SeverityInfo {
+		t.Errorf("Expected %v but result was %v", SeverityInfo, r)
+	}
+	tests := map[Severity]string{
+		SeverityDebug: "DEBUG 0\nINFO  1\nWARN  2\nERROR 3\n",
+		SeverityInfo:  "INFO  1\nWARN  2\nERROR 3\n",
+		SeverityWarn:  "WARN  2\nERROR 3\n",
+		SeverityError: "ERROR 3\n",
+	}
+	for s, exp := range tests {
+		b.Reset()
+		l.SetLevel(s)
+		if r := l.Level(); r != s {
+			t.Errorf("Expected %v but result was %v", s, r)
+		}
+		l.Debug(0)
+		l.Info(1)
+		l.Warn(2)
+		l.Error(3)
+		if b.String() != exp {
+			t.Errorf("Expected %q but result was %q", exp, b.String())
+		}
+	}
+}
+
+func TestLevelStyle(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.Ansi256)
+	l.Warnf("%h[bold]foo")
+	exp := color.RunLevel("%h[fgYellow+bold]WARN%r  %h[bold]foo", color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	l.SetLevelStyle(SeverityWarn, "fgMagenta")
+	l.Warn("foo")
+	exp = color.RunLevel("%h[fgMagenta]WARN%r  foo", color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	l.SetLevelStyle(SeverityWarn, "")
+	l.Warn("foo")
+	exp = "WARN  foo\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestSeverityString(t *testing.T) {
+	t.Parallel()
+	tests := map[Severity]string{
+		SeverityDebug: "DEBUG",
+		SeverityInfo:  "INFO",
+		SeverityWarn:  "WARN",
+		SeverityError: "ERROR",
+		Severity(7):   "Severity(7)",
+	}
+	for s, exp := range tests {
+		if r := s.String(); r != exp {
+			t.Errorf("Expected %q but result was %q", exp, r)
+		}
+	}
+}
@@ -3,8 +3,54 @@ Package log implements a simple logging package with support for highlight verbs
 It defines a Logger type with methods for formatting and printing output.
 
 It also defines a global standard Logger that writes to standard error. Color output
-will only be enabled if standard error is a terminal.
-Use the helper functions Print[f|ln|p], Fatal[f|ln|p], Panicf[f|ln|p], SetOutput and SetColor to access it.
+will only be enabled if color.Detect reports that standard error supports it.
+Use the helper functions Print[f|ln|p], Fatal[f|ln|p], Panicf[f|ln|p], SetOutput, SetColor, SetColorLevel, SetTerminal, SetFlags, SetPrefix and SetEncoding to access it.
+
+Messages can also be logged with a Severity using the Debug, Info, Warn and Error families.
+They are prefixed with a tag naming the severity, highlighted in a style that can be changed
+with SetLevelStyle, and discarded if the severity is lower than the one set with SetLevel.
+
+	log.SetLevel(log.SeverityWarn)
+	log.Infof("connected to %s", addr) // discarded
+	log.Warnf("%h[bold]retrying%r in %v", d) // "WARN  retrying in 1s" with a yellow "WARN"
+
+Fields:
+
+With returns a child Logger that logs key/value fields after each of those messages, and
+the Debugw, Infow, Warnw and Errorw functions take more fields after the message. Fields
+are written like logfmt, with the keys and values highlighted in the styles set with
+SetFieldStyle, and every entry stays on a single line.
+
+	reqLog := log.With("user", "bob")
+	reqLog.Infow("request", "path", "/", "status", 200) // "INFO  request user=bob path=/ status=200"
+
+Headers:
+
+Like in the log package of the standard library, SetFlags and SetPrefix add the date,
+time, caller and a prefix before each message, although none are added by default.
+The prefix can contain highlight verbs and the other components are highlighted in the
+styles set with SetFlagStyle.
+
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	log.SetPrefix("%h[fgMagenta]api:%r ")
+	log.SetFlagStyle(log.Lshortfile, "fgBlue")
+	log.Info("started") // "api: 2009/01/23 01:23:23 main.go:12: INFO  started"
+
+Encodings:
+
+By default entries are written for people to read, but SetEncoding switches a Logger to
+the Logfmt or JSON encodings for log collectors. They never contain control sequences:
+the highlight verbs and Format arguments are stripped.
+
+	log.SetEncoding(log.JSON)
+	log.Infow("request", "status", 200) // {"level":"info","msg":"request","status":200}
+
+Structured Logging:
+
+Handler adapts a Logger to log/slog, so programs that use slog get the same output.
+
+	slog.SetDefault(slog.New(log.NewHandler(os.Stderr, nil)))
+	slog.Info("request", "method", "GET") // "INFO  request method=GET"
 */
 package log
 
@@ -12,7 +58,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/nhooyr/color"
 )
@@ -21,129 +71,283 @@ import (
 type Logger struct {
 	out *lineWriter // ensures output is written on separate lines
 
-	mu    sync.Mutex
-	color bool // enable color output
+	mu     sync.Mutex
+	config // copied before each entry is written so that l.mu is not held while writing it
+}
+
+// config holds the settings of a Logger.
+type config struct {
+	term         *color.Terminal                  // terminal the control sequences are for
+	colorLevel   color.Level                      // level of color support
+	level        Severity                         // minimum severity of logged messages
+	tags         [SeverityError + 1]*color.Format // tag written before the messages of each severity
+	fields       []field                          // fields logged after the messages
+	keyStyle     *color.Format                    // attributes of the keys of fields, nil for none
+	valueStyle   *color.Format                    // attributes of the values of fields, nil for none
+	flags        int                              // properties of the header
+	prefix       string                           // prefix as passed to SetPrefix
+	prefixFormat *color.Format                    // prefix with its highlight verbs processed, nil if empty
+	flagStyles   [fileStyle + 1]*color.Format     // attributes of the components of the header, nil for none
+	encoding     Encoding                         // format of the entries
 }
 
 // New creates a new Logger. The out argument sets the
 // destination to which log data will be written.
 // The color argument dictates whether color output is enabled.
+// If it is, the level of color support reported by color.TermLevel is used.
 func New(w io.Writer, color bool) *Logger {
-	return &Logger{out: &lineWriter{w: w}, color: color}
+	return NewColorLevel(w, levelOf(color))
+}
+
+// NewColorLevel is the same as New but takes the level of color support.
+func NewColorLevel(w io.Writer, l color.Level) *Logger {
+	return &Logger{
+		out: &lineWriter{w: w},
+		config: config{
+			term:       color.DefaultTerminal(),
+			colorLevel: l,
+			level:      SeverityInfo,
+			tags:       defaultTags,
+			keyStyle:   defaultKeyStyle,
+			valueStyle: defaultValueStyle,
+			flagStyles: defaultFlagStyles,
+		},
+	}
+}
+
+// levelOf returns the level of color support for a color argument.
+func levelOf(c bool) color.Level {
+	if c {
+		return color.TermLevel()
+	}
+	return color.None
 }
 
 // Printf processes the highlight verbs in format and then calls
 // fmt.Fprintf to print to the underlying writer.
 // It will expand each Format in v to its appropriate string before calling fmt.Fprintf.
 func (l *Logger) Printf(format string, v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	format = color.Run(format, l.color)
-	l.mu.Unlock()
-	fmt.Fprintf(l.out, format, v...)
+	l.logf(noSeverity, format, v)
 }
 
 // Printfp is the same as l.Printf but takes a prepared format struct.
 func (l *Logger) Printfp(f *color.Format, v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	format := f.Get(l.color)
-	l.mu.Unlock()
-	fmt.Fprintf(l.out, format, v...)
+	l.logfp(noSeverity, f, v)
 }
 
 // Print calls fmt.Fprint to print to the underlying writer.
 // It will expand each Format in v to its appropriate string before calling fmt.Fprint.
 func (l *Logger) Print(v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	l.mu.Unlock()
-	fmt.Fprint(l.out, v...)
+	l.log(noSeverity, v)
 }
 
 // Println calls fmt.Fprintln to print to the underlying writer.
 // It will expand each Format in v to its appropriate string before calling fmt.Fprintln.
 func (l *Logger) Println(v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	l.mu.Unlock()
-	fmt.Fprintln(l.out, v...)
+	l.logln(noSeverity, v)
 }
 
 // Fatalf is equivalent to l.Printf() followed by a call to os.Exit(1).
 func (l *Logger) Fatalf(format string, v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	format = color.Run(format, l.color)
-	fmt.Fprintf(l.out, format, v...)
+	l.logf(noSeverity, format, v)
 	os.Exit(1)
 }
 
 // Fatalfp is the same as l.Fatalf but takes a prepared format struct.
 func (l *Logger) Fatalfp(f *color.Format, v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	format := f.Get(l.color)
-	fmt.Fprintf(l.out, format, v...)
+	l.logfp(noSeverity, f, v)
 	os.Exit(1)
 }
 
 // Fatal is equivalent to l.Print() followed by a call to os.Exit(1).
 func (l *Logger) Fatal(v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	fmt.Fprint(l.out, v...)
+	l.log(noSeverity, v)
 	os.Exit(1)
 }
 
 // Fatalln is equivalent to l.Println() followed by a call to os.Exit(1).
 func (l *Logger) Fatalln(v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	fmt.Fprintln(l.out, v...)
+	l.logln(noSeverity, v)
 	os.Exit(1)
 }
 
 // Panicf is equivalent to l.Printf() followed by a call to panic().
 func (l *Logger) Panicf(format string, v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	format = color.Run(format, l.color)
-	l.mu.Unlock()
-	s := fmt.Sprintf(format, v...)
-	l.out.WriteString(s)
-	panic(s)
+	panic(l.logf(noSeverity, format, v))
 }
 
 // Panicfp is the same as l.Panicf but takes a prepared format struct.
 func (l *Logger) Panicfp(f *color.Format, v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	format := f.Get(l.color)
-	l.mu.Unlock()
-	s := fmt.Sprintf(format, v...)
-	l.out.WriteString(s)
-	panic(s)
+	panic(l.logfp(noSeverity, f, v))
 }
 
 // Panic is equivalent to l.Print() followed by a call to panic().
 func (l *Logger) Panic(v ...interface{}) {
-	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	l.mu.Unlock()
-	s := fmt.Sprint(v...)
-	l.out.WriteString(s)
-	panic(s)
+	panic(l.log(noSeverity, v))
 }
 
 // Panicln is equivalent to l.Println() followed by a call to panic().
 func (l *Logger) Panicln(v ...interface{}) {
+	panic(l.logln(noSeverity, v))
+}
+
+// The exported functions and methods call these to log a message of severity s,
+// or noSeverity for the Print, Fatal and Panic families. They return the message.
+// Each is called directly by the exported function or method so that the caller
+// reported with the Lshortfile and Llongfile flags is always callDepth frames up.
+
+// logf logs a message like l.Printf.
+func (l *Logger) logf(s Severity, format string, v []interface{}) string {
+	c, ok := l.load(s)
+	if !ok {
+		return ""
+	}
+	args := c.expand(v)
+	msg := fmt.Sprintf(c.term.Run(format, c.colorLevel), *args...)
+	freeArgs(args)
+	l.output(&c, s, msg, nil)
+	return msg
+}
+
+// logfp logs a message like l.Printfp.
+func (l *Logger) logfp(s Severity, f *color.Format, v []interface{}) string {
+	c, ok := l.load(s)
+	if !ok {
+		return ""
+	}
+	args := c.expand(v)
+	msg := fmt.Sprintf(f.GetTerminal(c.term, c.colorLevel), *args...)
+	freeArgs(args)
+	l.output(&c, s, msg, nil)
+	return msg
+}
+
+// log logs a message like l.Print.
+func (l *Logger) log(s Severity, v []interface{}) string {
+	c, ok := l.load(s)
+	if !ok {
+		return ""
+	}
+	args := c.expand(v)
+	msg := fmt.Sprint(*args...)
+	freeArgs(args)
+	l.output(&c, s, msg, nil)
+	return msg
+}
+
+// logln logs a message like l.Println.
+func (l *Logger) logln(s Severity, v []interface{}) string {
+	c, ok := l.load(s)
+	if !ok {
+		return ""
+	}
+	args := c.expand(v)
+	msg := fmt.Sprintln(*args...)
+	freeArgs(args)
+	l.output(&c, s, msg, nil)
+	return msg
+}
+
+// logw logs msg followed by the fields of l and those in kv.
+func (l *Logger) logw(s Severity, msg string, kv []interface{}) string {
+	c, ok := l.load(s)
+	if !ok {
+		return ""
+	}
+	l.output(&c, s, msg, kv)
+	return msg
+}
+
+// load returns a copy of the config of l, or false if messages of severity s are discarded.
+// The copy has no color support if the encoding of l is not Console.
+func (l *Logger) load(s Severity) (c config, ok bool) {
 	l.mu.Lock()
-	color.ExpandFormats(l.color, v)
-	l.mu.Unlock()
-	s := fmt.Sprintln(v...)
-	l.out.WriteString(s)
-	panic(s)
+	defer l.mu.Unlock()
+	if s != noSeverity && s < l.level {
+		return c, false
+	}
+	c = l.config
+	if c.encoding != Console {
+		c.colorLevel = color.None
+	}
+	return c, true
+}
+
+// entry is a log entry.
+type entry struct {
+	time     time.Time     // zero unless the flags include Ldate, Ltime or Lmicroseconds
+	file     string        // empty unless the flags include Llongfile or Lshortfile
+	line     int           // line number in file
+	severity Severity      // severity of the message
+	msg      string        // message without a trailing newline
+	kv       []interface{} // alternating keys and values of fields logged after those of the Logger
+}
+
+// caller returns the file name and line number of e.
+func (e *entry) caller() string {
+	return e.file + ":" + strconv.Itoa(e.line)
+}
+
+// bufPool holds buffers for encoding entries.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// output writes an entry of severity s with the message msg and the fields in kv,
+// encoded with c. It must be called by the helper called by the exported function or method.
+func (l *Logger) output(c *config, s Severity, msg string, kv []interface{}) {
+	e := entry{severity: s, msg: strings.TrimSuffix(msg, "\n"), kv: kv}
+	if c.flags&(Ldate|Ltime|Lmicroseconds) != 0 {
+		e.time = time.Now()
+		if c.flags&LUTC != 0 {
+			e.time = e.time.UTC()
+		}
+	}
+	if c.flags&(Llongfile|Lshortfile) != 0 {
+		var ok bool
+		_, e.file, e.line, ok = runtime.Caller(callDepth)
+		if !ok {
+			e.file, e.line = "???", 0
+		}
+		if c.flags&Lshortfile != 0 {
+			e.file = e.file[strings.LastIndexByte(e.file, '/')+1:]
+		}
+	}
+	l.write(c, &e)
+}
+
+// write writes e encoded with c as a single line.
+func (l *Logger) write(c *config, e *entry) error {
+	b := bufPool.Get().(*[]byte)
+	*b = c.encode((*b)[:0], e)
+	_, err := l.out.Write(*b)
+	bufPool.Put(b)
+	return err
+}
+
+// argsPool holds slices for the expanded arguments of Loggers.
+var argsPool = sync.Pool{
+	New: func() interface{} {
+		return new([]interface{})
+	},
+}
+
+// expand returns a pooled copy of v with each Format replaced by its string for the
+// terminal and level of color support of c. Return the copy with freeArgs.
+func (c *config) expand(v []interface{}) *[]interface{} {
+	args := argsPool.Get().(*[]interface{})
+	*args = color.AppendFormats((*args)[:0], c.term, c.colorLevel, v...)
+	return args
+}
+
+// freeArgs clears args so it does not keep the arguments alive and returns it to the pool.
+func freeArgs(args *[]interface{}) {
+	for i := range *args {
+		(*args)[i] = nil
+	}
+	argsPool.Put(args)
 }
 
 // SetOutput sets the output destination.
@@ -154,10 +358,30 @@ func (l *Logger) SetOutput(w io.Writer) {
 }
 
 // SetColor sets whether colored output is enabled.
+// If it is, the level of color support reported by color.TermLevel is used.
 func (l *Logger) SetColor(color bool) {
+	l.SetColorLevel(levelOf(color))
+}
+
+// SetColorLevel sets the level of color support.
+func (l *Logger) SetColorLevel(level color.Level) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.color = color
+	l.colorLevel = level
+}
+
+// SetTerminal sets the terminal whose control sequences are written.
+func (l *Logger) SetTerminal(t *color.Terminal) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.term = t
+}
+
+// ColorLevel returns the level of color support.
+func (l *Logger) ColorLevel() color.Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.colorLevel
 }
 
 // lineWriter ensures that each Write to the underlying writer will end on a newline.
@@ -189,66 +413,70 @@ func (lw *lineWriter) WriteString(s string) (n int, err error) {
 	return io.WriteString(lw.w, s)
 }
 
-var std = New(os.Stderr, color.IsTerminal(os.Stderr))
+var std = NewColorLevel(os.Stderr, color.Detect(os.Stderr))
 
 // Printf calls the standard Logger's Printf method.
 func Printf(format string, v ...interface{}) {
-	std.Printf(format, v...)
+	std.logf(noSeverity, format, v)
 }
 
 // Printfp calls the standard Logger's Printfp method.
 func Printfp(f *color.Format, v ...interface{}) {
-	std.Printfp(f, v...)
+	std.logfp(noSeverity, f, v)
 }
 
-// Print calls the standard Logger's Printf method.
+// Print calls the standard Logger's Print method.
 func Print(v ...interface{}) {
-	std.Print(v...)
+	std.log(noSeverity, v)
 }
 
 // Println calls the standard Logger's Println method.
 func Println(v ...interface{}) {
-	std.Println(v...)
+	std.logln(noSeverity, v)
 }
 
 // Fatalf calls the standard Logger's Fatalf method.
 func Fatalf(format string, v ...interface{}) {
-	std.Fatalf(format, v...)
+	std.logf(noSeverity, format, v)
+	os.Exit(1)
 }
 
 // Fatalfp calls the standard Logger's Fatalfp method.
 func Fatalfp(f *color.Format, v ...interface{}) {
-	std.Fatalfp(f, v...)
+	std.logfp(noSeverity, f, v)
+	os.Exit(1)
 }
 
 // Fatal calls the standard Logger's Fatal method.
 func Fatal(v ...interface{}) {
-	std.Fatal(v...)
+	std.log(noSeverity, v)
+	os.Exit(1)
 }
 
 // Fatalln calls the standard Logger's Fatalln method.
 func Fatalln(v ...interface{}) {
-	std.Fatalln(v...)
+	std.logln(noSeverity, v)
+	os.Exit(1)
 }
 
 // Panicf calls the standard Logger's Panicf method.
 func Panicf(format string, v ...interface{}) {
-	std.Panicf(format, v...)
+	panic(std.logf(noSeverity, format, v))
 }
 
 // Panicfp calls the standard Logger's Panicfp method.
 func Panicfp(f *color.Format, v ...interface{}) {
-	std.Panicfp(f, v...)
+	panic(std.logfp(noSeverity, f, v))
 }
 
 // Panic calls the standard Logger's Panic method.
 func Panic(v ...interface{}) {
-	std.Panic(v...)
+	panic(std.log(noSeverity, v))
 }
 
 // Panicln calls the standard Logger's Panicln method.
 func Panicln(v ...interface{}) {
-	std.Panicln(v...)
+	panic(std.logln(noSeverity, v))
 }
 
 // SetOutput sets the output destination of the standard Logger.
@@ -260,3 +488,149 @@ func SetOutput(w io.Writer) {
 func SetColor(color bool) {
 	std.SetColor(color)
 }
+
+// SetColorLevel sets the level of color support for the standard Logger.
+func SetColorLevel(level color.Level) {
+	std.SetColorLevel(level)
+}
+
+// SetTerminal sets the terminal of the standard Logger.
+func SetTerminal(t *color.Terminal) {
+	std.SetTerminal(t)
+}
+
+// Debugf calls the standard Logger's Debugf method.
+func Debugf(format string, v ...interface{}) {
+	std.logf(SeverityDebug, format, v)
+}
+
+// Debugfp calls the standard Logger's Debugfp method.
+func Debugfp(f *color.Format, v ...interface{}) {
+	std.logfp(SeverityDebug, f, v)
+}
+
+// Debug calls the standard Logger's Debug method.
+func Debug(v ...interface{}) {
+	std.log(SeverityDebug, v)
+}
+
+// Debugw calls the standard Logger's Debugw method.
+func Debugw(msg string, kv ...interface{}) {
+	std.logw(SeverityDebug, msg, kv)
+}
+
+// Infof calls the standard Logger's Infof method.
+func Infof(format string, v ...interface{}) {
+	std.logf(SeverityInfo, format, v)
+}
+
+// Infofp calls the standard Logger's Infofp method.
+func Infofp(f *color.Format, v ...interface{}) {
+	std.logfp(SeverityInfo, f, v)
+}
+
+// Info calls the standard Logger's Info method.
+func Info(v ...interface{}) {
+	std.log(SeverityInfo, v)
+}
+
+// Infow calls the standard Logger's Infow method.
+func Infow(msg string, kv ...interface{}) {
+	std.logw(SeverityInfo, msg, kv)
+}
+
+// Warnf calls the standard Logger's Warnf method.
+func Warnf(format string, v ...interface{}) {
+	std.logf(SeverityWarn, format, v)
+}
+
+// Warnfp calls the standard Logger's Warnfp method.
+func Warnfp(f *color.Format, v ...interface{}) {
+	std.logfp(SeverityWarn, f, v)
+}
+
+// Warn calls the standard Logger's Warn method.
+func Warn(v ...interface{}) {
+	std.log(SeverityWarn, v)
+}
+
+// Warnw calls the standard Logger's Warnw method.
+func Warnw(msg string, kv ...interface{}) {
+	std.logw(SeverityWarn, msg, kv)
+}
+
+// Errorf calls the standard Logger's Errorf method.
+func Errorf(format string, v ...interface{}) {
+	std.logf(SeverityError, format, v)
+}
+
+// Errorfp calls the standard Logger's Errorfp method.
+func Errorfp(f *color.Format, v ...interface{}) {
+	std.logfp(SeverityError, f, v)
+}
+
+// Error calls the standard Logger's Error method.
+func Error(v ...interface{}) {
+	std.log(SeverityError, v)
+}
+
+// Errorw calls the standard Logger's Errorw method.
+func Errorw(msg string, kv ...interface{}) {
+	std.logw(SeverityError, msg, kv)
+}
+
+// SetLevel sets the minimum severity of the messages the standard Logger logs.
+func SetLevel(s Severity) {
+	std.SetLevel(s)
+}
+
+// Level returns the minimum severity of the messages the standard Logger logs.
+func Level() Severity {
+	return std.Level()
+}
+
+// SetLevelStyle sets the attributes of the tag of severity s for the standard Logger.
+func SetLevelStyle(s Severity, attrs string) {
+	std.SetLevelStyle(s, attrs)
+}
+
+// With calls the standard Logger's With method.
+func With(kv ...interface{}) *Logger {
+	return std.With(kv...)
+}
+
+// SetEncoding sets the encoding of the entries of the standard Logger.
+func SetEncoding(e Encoding) {
+	std.SetEncoding(e)
+}
+
+// SetFlags sets the flags of the standard Logger.
+func SetFlags(flag int) {
+	std.SetFlags(flag)
+}
+
+// Flags returns the flags of the standard Logger.
+func Flags() int {
+	return std.Flags()
+}
+
+// SetPrefix sets the prefix of the standard Logger.
+func SetPrefix(prefix string) {
+	std.SetPrefix(prefix)
+}
+
+// Prefix returns the prefix of the standard Logger.
+func Prefix() string {
+	return std.Prefix()
+}
+
+// SetFlagStyle sets the attributes of the component of the header selected by flag for the
+// standard Logger.
+func SetFlagStyle(flag int, attrs string) {
+	std.SetFlagStyle(flag, attrs)
+}
+
+// SetFieldStyle sets the attributes of the keys and values of fields for the standard Logger.
+func SetFieldStyle(key, value string) {
+	std.SetFieldStyle(key, value)
+}
@@ -72,6 +72,66 @@ func TestPrintAndPrintln(t *testing.T) {
 	}
 }
 
+func TestSetColorLevel(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	l := New(&b, false)
+	f := color.Prepare("%h[fg#ff0000]bar")
+	for level := color.None; level <= color.TrueColor; level++ {
+		b.Reset()
+		l.SetColorLevel(level)
+		if r := l.ColorLevel(); r != level {
+			t.Errorf("Expected %v but result was %v", level, r)
+		}
+		exp := f.GetLevel(level) + "\n"
+		l.Print(f)
+		if b.String() != exp {
+			t.Errorf("Expected %q but result was %q", exp, b.String())
+		}
+	}
+}
+
+func TestSetTerminal(t *testing.T) {
+	t.Parallel()
+	term, err := color.LoadTerminal("linux")
+	if err != nil {
+		t.Skip(err)
+	}
+	var b bytes.Buffer
+	l := NewColorLevel(&b, color.Ansi256)
+	l.SetTerminal(term)
+	f := color.Prepare("%h[fg196]bar")
+	l.Printf("%h[bold]foo %v", f)
+	exp := term.Run("%h[bold]foo ", color.Ansi256) + f.GetTerminal(term, color.Ansi256) + "\n"
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestArgsReuse(t *testing.T) {
+	t.Parallel()
+	var colored, stripped bytes.Buffer
+	lc := New(&colored, true)
+	ls := New(&stripped, false)
+	inner := color.Prepare("%h[fgGreen]rip")
+	v := []interface{}{inner, 3}
+	lc.Printf("%s %d", v...)
+	ls.Printf("%s %d", v...)
+	lc.Println(v...)
+	ls.Println(v...)
+	if v[0] != inner {
+		t.Errorf("Expected the arguments to be left unchanged but result was %v", v)
+	}
+	exp := "rip 3\nrip 3\n"
+	if stripped.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, stripped.String())
+	}
+	exp = inner.Get(true) + " 3\n" + inner.Get(true) + " 3\n"
+	if colored.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, colored.String())
+	}
+}
+
 func TestPanic(t *testing.T) {
 	t.Parallel()
 	var b bytes.Buffer
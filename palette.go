@@ -0,0 +1,98 @@
+package color
+
+import "math"
+
+// palette holds the RGB values of the 256 colors in xterm's default palette.
+// The first 16 entries are the named colors, followed by a 6x6x6 color cube
+// and then a grayscale ramp.
+var palette = newPalette()
+
+// newPalette returns xterm's default 256 color palette.
+func newPalette() (p [256][3]uint8) {
+	named := [16][3]uint8{
+		{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+		{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+		{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+		{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+	}
+	copy(p[:], named[:])
+	levels := [6]uint8{0, 95, 135, 175, 215, 255}
+	for i := 0; i < 216; i++ {
+		p[16+i] = [3]uint8{levels[i/36], levels[i/6%6], levels[i%6]}
+	}
+	for i := 0; i < 24; i++ {
+		v := uint8(8 + i*10)
+		p[232+i] = [3]uint8{v, v, v}
+	}
+	return p
+}
+
+// paletteLab holds the palette converted into the CIELAB color space.
+var paletteLab = newPaletteLab()
+
+func newPaletteLab() (p [256][3]float64) {
+	for i, c := range palette {
+		p[i] = lab(c[0], c[1], c[2])
+	}
+	return p
+}
+
+// fitColor returns the palette color perceptually closest to r, g and b
+// among the first n colors of the palette.
+// If the full 256 color palette is available, the named colors are skipped
+// because terminals commonly change them through themes.
+func fitColor(r, g, b uint8, n int) int {
+	start := 0
+	if n >= len(palette) {
+		start, n = 16, len(palette)
+	}
+	c := lab(r, g, b)
+	best, bestDist := start, math.Inf(1)
+	for i := start; i < n; i++ {
+		if d := labDistance(c, paletteLab[i]); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// fitPaletteColor returns the color closest to the palette color c
+// among the first n colors of the palette.
+func fitPaletteColor(c, n int) int {
+	if c < n {
+		return c
+	}
+	return fitColor(palette[c][0], palette[c][1], palette[c][2], n)
+}
+
+// labDistance returns the squared CIE76 color difference between a and b.
+func labDistance(a, b [3]float64) float64 {
+	dl, da, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dl*dl + da*da + db*db
+}
+
+// lab converts an sRGB color into the CIELAB color space using the D65 white point.
+func lab(r, g, b uint8) [3]float64 {
+	lr, lg, lb := linear(r), linear(g), linear(b)
+	x := (0.4124*lr + 0.3576*lg + 0.1805*lb) / 0.95047
+	y := 0.2126*lr + 0.7152*lg + 0.0722*lb
+	z := (0.0193*lr + 0.1192*lg + 0.9505*lb) / 1.08883
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// linear converts an sRGB component into linear light.
+func linear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	if t > 216.0/24389 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27*t + 16) / 116
+}
@@ -0,0 +1,47 @@
+package color
+
+import "testing"
+
+var fitColorCases = []struct {
+	r, g, b uint8
+	n       int
+	exp     int
+}{
+	{255, 0, 0, 256, 196},
+	{255, 0, 0, 16, 9},
+	{255, 0, 0, 8, 1},
+	{128, 128, 128, 256, 244},
+	{255, 136, 0, 256, 208},
+	{0, 0, 0, 256, 16},
+	{255, 255, 255, 16, 15},
+	{255, 255, 255, 8, 7},
+}
+
+func TestFitColor(t *testing.T) {
+	t.Parallel()
+	for _, c := range fitColorCases {
+		if r := fitColor(c.r, c.g, c.b, c.n); r != c.exp {
+			t.Errorf("Expected %d from rgb(%d,%d,%d) in %d colors but result was %d", c.exp, c.r, c.g, c.b, c.n, r)
+		}
+	}
+}
+
+var fitPaletteColorCases = []struct {
+	c, n, exp int
+}{
+	{196, 256, 196},
+	{196, 16, 9},
+	{196, 8, 1},
+	{12, 8, 4},
+	{3, 8, 3},
+	{232, 16, 0},
+}
+
+func TestFitPaletteColor(t *testing.T) {
+	t.Parallel()
+	for _, c := range fitPaletteColorCases {
+		if r := fitPaletteColor(c.c, c.n); r != c.exp {
+			t.Errorf("Expected %d from %d in %d colors but result was %d", c.exp, c.c, c.n, r)
+		}
+	}
+}
@@ -11,13 +11,38 @@ import (
 // Printer prints to a writer using highlight verbs.
 type Printer struct {
 	out   io.Writer // underlying writer
-	color bool      // enable color output
+	term  *Terminal // terminal the control sequences are for
+	level Level     // level of color support
 }
 
 // New creates a new Printer that writes to out.
 // The color argument dictates whether color output is enabled.
+// If it is, the level of color support reported by TermLevel is used.
 func New(out io.Writer, color bool) *Printer {
-	return &Printer{out, color}
+	return NewLevel(out, levelOf(color))
+}
+
+// NewLevel creates a new Printer that writes to out with the level of color support l.
+// Colors are downsampled to the closest ones supported by l and color output is
+// disabled if l is None.
+func NewLevel(out io.Writer, l Level) *Printer {
+	return NewTerminal(out, defaultTerminal, l)
+}
+
+// NewTerminal creates a new Printer that writes to out with the control sequences
+// of the terminal t and the level of color support l.
+func NewTerminal(out io.Writer, t *Terminal, l Level) *Printer {
+	return &Printer{out, t, l}
+}
+
+// Level returns the level of color support of p.
+func (p *Printer) Level() Level {
+	return p.level
+}
+
+// Terminal returns the terminal of p.
+func (p *Printer) Terminal() *Terminal {
+	return p.term
 }
 
 // Printf first processes the highlight verbs in format and then calls
@@ -25,28 +50,34 @@ func New(out io.Writer, color bool) *Printer {
 // It will expand each Format in a to its appropriate string before calling fmt.Fprintf.
 // It returns the number of bytes written an any write error encountered.
 func (p *Printer) Printf(format string, a ...interface{}) (n int, err error) {
-	ExpandFormats(p.color, a)
-	return fmt.Fprintf(p.out, Run(format, p.color), a...)
+	format = p.term.Run(format, p.level)
+	args := expandArgs(p.term, p.level, argVerbs(format), a)
+	defer freeArgs(args)
+	return fmt.Fprintf(p.out, format, *args...)
 }
 
 // Printfp is the same as p.Printf but takes a prepared format struct.
 func (p *Printer) Printfp(f *Format, a ...interface{}) (n int, err error) {
-	ExpandFormats(p.color, a)
-	return fmt.Fprintf(p.out, f.Get(p.color), a...)
+	format := f.GetTerminal(p.term, p.level)
+	args := expandArgs(p.term, p.level, argVerbs(format), a)
+	defer freeArgs(args)
+	return fmt.Fprintf(p.out, format, *args...)
 }
 
 // Print calls fmt.Fprint to print to the underlying writer.
 // It will expand each Format in a to its appropriate string before calling fmt.Fprint.
 func (p *Printer) Print(a ...interface{}) (n int, err error) {
-	ExpandFormats(p.color, a)
-	return fmt.Fprint(p.out, a...)
+	args := expandArgs(p.term, p.level, nil, a)
+	defer freeArgs(args)
+	return fmt.Fprint(p.out, *args...)
 }
 
 // Println calls fmt.Fprintln to print to the underlying writer.
 // It will expand each Format in a to its appropriate string before calling fmt.Fprintln.
 func (p *Printer) Println(a ...interface{}) (n int, err error) {
-	ExpandFormats(p.color, a)
-	return fmt.Fprintln(p.out, a...)
+	args := expandArgs(p.term, p.level, nil, a)
+	defer freeArgs(args)
+	return fmt.Fprintln(p.out, *args...)
 }
 
 // IsTerminal returns true if f is a terminal and false otherwise.
@@ -54,7 +85,7 @@ func IsTerminal(f *os.File) bool {
 	return terminal.IsTerminal(int(f.Fd()))
 }
 
-var std = New(os.Stdout, IsTerminal(os.Stdout))
+var std = NewLevel(os.Stdout, Detect(os.Stdout))
 
 // Printf calls the standard output Printer's Printf method.
 func Printf(format string, a ...interface{}) (n int, err error) {
@@ -3,6 +3,7 @@ package color
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -84,3 +85,107 @@ func TestPrintln(t *testing.T) {
 		t.Errorf("Expected %q but result was %q", exp, b.String())
 	}
 }
+
+func TestPrintfScopes(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	f := Prepare("%h[fgGreen]rip%h[/]")
+	exp := Highlight("%h[bold]panic: %h[fgGreen]rip%h[/]!%h[/]\n")
+	p := New(&b, true)
+	p.Printf("%h[bold]panic: %s!%h[/]\n", f)
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	exp = "panic: rip!\n"
+	p = New(&b, false)
+	p.Printf("%h[bold]panic: %s!%h[/]\n", f)
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
+
+func TestNewLevel(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	const s = "%h[fg#ff0000]%s%r"
+	f := Prepare("%h[bg196]bar")
+	for l := None; l <= TrueColor; l++ {
+		b.Reset()
+		exp := fmt.Sprintf(RunLevel(s, l), f.GetLevel(l))
+		p := NewLevel(&b, l)
+		if p.Level() != l {
+			t.Errorf("Expected %v but result was %v", l, p.Level())
+		}
+		p.Printf(s, f)
+		if b.String() != exp {
+			t.Errorf("Expected %q at %v but result was %q", exp, l, b.String())
+		}
+	}
+}
+
+func TestPrinterArgsReuse(t *testing.T) {
+	t.Parallel()
+	var colored, stripped bytes.Buffer
+	pc := New(&colored, true)
+	ps := New(&stripped, false)
+	f := Prepare("%h[fgRed]%s %d%r\n")
+	inner := Prepare("%h[fgGreen]rip")
+	a := []interface{}{inner, 3}
+	for _, printArgs := range []func(p *Printer){
+		func(p *Printer) { p.Printf("%h[fgRed]%s %d%r\n", a...) },
+		func(p *Printer) { p.Printfp(f, a...) },
+		func(p *Printer) { p.Print(a...) },
+		func(p *Printer) { p.Println(a...) },
+	} {
+		colored.Reset()
+		stripped.Reset()
+		printArgs(pc)
+		printArgs(ps)
+		if a[0] != inner {
+			t.Errorf("Expected the arguments to be left unchanged but result was %v", a)
+		}
+		if strings.Contains(stripped.String(), "\x1b") {
+			t.Errorf("Expected no control sequences but result was %q", stripped.String())
+		}
+		if !strings.Contains(stripped.String(), "rip") {
+			t.Errorf("Expected %q in %q", "rip", stripped.String())
+		}
+		if exp := inner.Get(true); !strings.Contains(colored.String(), exp) {
+			t.Errorf("Expected %q in %q", exp, colored.String())
+		}
+	}
+}
+
+func TestPrintfPadding(t *testing.T) {
+	t.Parallel()
+	f := Prepare("%h[fgRed+bold]error%r")
+	for _, color := range []bool{true, false} {
+		var b bytes.Buffer
+		p := New(&b, color)
+		p.Printf("%-8s|%6s|%-6s|%.3s|\n", f, "日本", Highlight("%h[bold]hi%r"), "日本語")
+		exp := fmt.Sprintf("%s   |  日本|%s    |日|\n", f.Get(color), Highlight("%h[bold]hi%r"))
+		if b.String() != exp {
+			t.Errorf("Expected %q but result was %q", exp, b.String())
+		}
+	}
+}
+
+func TestPrintfPaddingVerbs(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	p := New(&b, true)
+	s := Highlight("%h[bold]日本%r")
+	p.Printf("%T %q %s %v|%*s|\n", s, s, s, s, 5, "日本")
+	exp := fmt.Sprintf("string %q %s %s| 日本|\n", s, s, s)
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+	b.Reset()
+	p.Print(s, 3)
+	p.Println(s, 3)
+	exp = fmt.Sprint(s, 3) + fmt.Sprintln(s, 3)
+	if b.String() != exp {
+		t.Errorf("Expected %q but result was %q", exp, b.String())
+	}
+}
@@ -0,0 +1,137 @@
+package color
+
+import "strings"
+
+// segmentKind is the kind of a segment of a compiled format string.
+type segmentKind uint8
+
+const (
+	segText segmentKind = iota // literal text, e.g. "panic: "
+	segAttr                    // highlight verb that changes the attributes, e.g. "%h[fgRed]"
+	segVerb                    // fmt directive, e.g. "%-20s"
+)
+
+// segment is a piece of a compiled format string.
+type segment struct {
+	kind segmentKind
+	s    string
+}
+
+// compile splits the format string f into segments. The attributes of the highlight
+// verbs are only resolved when the segments are rendered, so one compilation can be
+// rendered for any terminal, level and set of styles.
+func compile(f string) []segment {
+	scoped := popsScope(f)
+	var segs []segment
+	text := func(s string) {
+		if n := len(segs); n > 0 && segs[n-1].kind == segText {
+			segs[n-1].s += s
+		} else {
+			segs = append(segs, segment{segText, s})
+		}
+	}
+	for len(f) > 0 {
+		i := strings.IndexByte(f, '%')
+		if i < 0 {
+			text(f)
+			break
+		}
+		if i > 0 {
+			text(f[:i])
+			f = f[i:]
+		}
+		n := verbLen(f, scoped)
+		switch {
+		case n == 1 || f[1] == '%':
+			text(f[:n])
+		case isHighlightVerb(f):
+			segs = append(segs, segment{segAttr, f[:n]})
+		default:
+			segs = append(segs, segment{segVerb, f[:n]})
+		}
+		f = f[n:]
+	}
+	return segs
+}
+
+// isHighlightVerb reports whether the verb at the start of f is a highlight verb.
+func isHighlightVerb(f string) bool {
+	switch f[1] {
+	case 'r', 'h', 'l', 'L':
+		return true
+	case '#':
+		return len(f) > 2 && f[2] == 'l'
+	}
+	return false
+}
+
+// verbLen returns the length of the verb at the start of f.
+// An invalid highlight verb extends to the end of f as the highlighter stops at it.
+// Like the highlighter, only scoped strings have their fmt directives scanned fully.
+func verbLen(f string, scoped bool) int {
+	if len(f) == 1 {
+		return 1
+	}
+	switch f[1] {
+	case 'r', 'L', '%':
+		return 2
+	case 'h', 'l':
+		return bracketLen(f, 2)
+	case '#':
+		if len(f) > 2 && f[2] == 'l' {
+			return bracketLen(f, 3)
+		}
+	}
+	if !scoped {
+		return 2
+	}
+	i := 1
+	for i < len(f) {
+		ch := f[i]
+		i++
+		if isVerb(ch) {
+			break
+		}
+	}
+	return i
+}
+
+// bracketLen returns the length of the bracketed argument starting at f[i], plus i.
+func bracketLen(f string, i int) int {
+	if i >= len(f) || f[i] != '[' {
+		return len(f)
+	}
+	end := strings.IndexByte(f[i:], ']')
+	if end < 0 {
+		return len(f)
+	}
+	return i + end + 1
+}
+
+// render renders the segments for the terminal t and the level of color support l in
+// a single pass. It is equivalent to t.Run on the format string the segments were
+// compiled from.
+func render(segs []segment, scoped bool, t *Terminal, l Level) string {
+	hl := newHighlighter("", t, l)
+	defer hl.free()
+	hl.scoped = scoped
+	for _, seg := range segs {
+		switch seg.kind {
+		case segText:
+			hl.buf.WriteString(seg.s)
+		case segAttr:
+			hl.s, hl.pos = seg.s, 0
+			hl.run()
+			if hl.err != "" {
+				// The highlighter stops at errors.
+				return hl.buf.String()
+			}
+		case segVerb:
+			hl.buf.WriteString(seg.s)
+			if hl.scoped {
+				hl.restore()
+			}
+		}
+	}
+	return hl.buf.String()
+}
@@ -0,0 +1,67 @@
+package color
+
+import "testing"
+
+var segmentCases = []map[string]string{
+	defaultColors,
+	underlineColors,
+	colorsRGB,
+	combinations,
+	highlightEdgeCases,
+	scopes,
+	links,
+	stripEdgeCases,
+	styleEdgeCases,
+	{
+		"%s %-20s %[1]d %%":  "",
+		"%h[bold]%-5s%h[/]":  "",
+		"%#x %#l[url]a%L":    "",
+		"%h[bold]%#%h[/]":    "",
+		"%-%r%h[/]":          "",
+		"100%":               "",
+		"%h":                 "",
+		"%h[fgRed":           "",
+		"%l[":                "",
+		"%h[bold]%h[lold]hi": "",
+	},
+}
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+	for _, m := range segmentCases {
+		for s := range m {
+			for l := None; l <= TrueColor; l++ {
+				exp := RunLevel(s, l)
+				if r := Prepare(s).GetLevel(l); r != exp {
+					t.Errorf("Expected %q from %q at %v but result was %q", exp, s, l, r)
+				}
+			}
+		}
+	}
+}
+
+var compiled = map[string][]segment{
+	"":                       nil,
+	"foo":                    {{segText, "foo"}},
+	"%h[fgRed]panic:%r %s\n": {{segAttr, "%h[fgRed]"}, {segText, "panic:"}, {segAttr, "%r"}, {segText, " "}, {segVerb, "%s"}, {segText, "\n"}},
+	"100%% %h[bold":          {{segText, "100%% "}, {segAttr, "%h[bold"}},
+	"%h[bold]%-5s%h[/]":      {{segAttr, "%h[bold]"}, {segVerb, "%-5s"}, {segAttr, "%h[/]"}},
+	"%-5s %#l[url]a%L":       {{segVerb, "%-"}, {segText, "5s "}, {segAttr, "%#l[url]"}, {segText, "a"}, {segAttr, "%L"}},
+}
+
+func TestCompileSegments(t *testing.T) {
+	t.Parallel()
+	for k, v := range compiled {
+		r := compile(k)
+		if len(r) != len(v) {
+			t.Errorf("Expected %v from %q but result was %v", v, k, r)
+			continue
+		}
+		for i := range r {
+			if r[i] != v[i] {
+				t.Errorf("Expected %v from %q but result was %v", v, k, r)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package color
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Color is a color for use in a Style. The zero value means the color is left unchanged.
+type Color uint32
+
+const (
+	colorIndexed Color = 1 << 24 // color in the 256 color palette
+	colorRGB     Color = 2 << 24 // 24-bit color
+	colorDefault Color = 3 << 24 // terminal's default color
+	colorKind    Color = 3 << 24
+)
+
+// Default is the terminal's default color.
+const Default = colorDefault
+
+// Named colors.
+const (
+	Black Color = colorIndexed + iota
+	Red
+	Green
+	Yellow
+	Blue
+	Magenta
+	Cyan
+	White
+	BrightBlack
+	BrightRed
+	BrightGreen
+	BrightYellow
+	BrightBlue
+	BrightMagenta
+	BrightCyan
+	BrightWhite
+)
+
+// colorNames holds the names of the named colors in order.
+var colorNames = [...]string{
+	"Black", "Red", "Green", "Yellow", "Blue", "Magenta", "Cyan", "White",
+	"BrightBlack", "BrightRed", "BrightGreen", "BrightYellow",
+	"BrightBlue", "BrightMagenta", "BrightCyan", "BrightWhite",
+}
+
+// Color256 returns color n of the 256 color palette.
+func Color256(n uint8) Color {
+	return colorIndexed | Color(n)
+}
+
+// RGB returns a 24-bit color.
+func RGB(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// attr returns c as an attribute with the given prefix, e.g. "fgRed" or "bg#ff8800".
+func (c Color) attr(prefix string) string {
+	switch c & colorKind {
+	case colorIndexed:
+		n := int(c & 0xff)
+		if n < len(colorNames) {
+			return prefix + colorNames[n]
+		}
+		return prefix + strconv.Itoa(n)
+	case colorRGB:
+		return fmt.Sprintf("%s#%06x", prefix, uint32(c&0xffffff))
+	case colorDefault:
+		return prefix + "Default"
+	}
+	return ""
+}
+
+// Style is a set of attributes that can be built programmatically instead of
+// written as a highlight verb. The zero value leaves all attributes unchanged.
+type Style struct {
+	FG Color // foreground color
+	BG Color // background color
+	UL Color // underline color
+
+	Bold            bool
+	Underline       bool
+	Reverse         bool
+	Blink           bool
+	Dim             bool
+	Italic          bool
+	Invisible       bool
+	Standout        bool
+	Strike          bool
+	DoubleUnderline bool
+	CurlyUnderline  bool
+	Overline        bool
+}
+
+// attrs returns the attributes of s in the syntax of the highlight verb.
+func (s Style) attrs() string {
+	var a []string
+	for _, c := range [...]string{s.FG.attr("fg"), s.BG.attr("bg"), s.UL.attr("ul")} {
+		if c != "" {
+			a = append(a, c)
+		}
+	}
+	modes := [...]struct {
+		on   bool
+		name string
+	}{
+		{s.Bold, "bold"},
+		{s.Underline, "underline"},
+		{s.Reverse, "reverse"},
+		{s.Blink, "blink"},
+		{s.Dim, "dim"},
+		{s.Italic, "italic"},
+		{s.Invisible, "invisible"},
+		{s.Standout, "standout"},
+		{s.Strike, "strike"},
+		{s.DoubleUnderline, "doubleunderline"},
+		{s.CurlyUnderline, "curlyunderline"},
+		{s.Overline, "overline"},
+	}
+	for _, m := range modes {
+		if m.on {
+			a = append(a, m.name)
+		}
+	}
+	return strings.Join(a, "+")
+}
+
+// Wrap returns a Format that sets the attributes of s, then prints str and
+// then resets all attributes. The result is the same as preparing
+// "%h[attrs...]" + str + "%r", but str is not processed for highlight verbs.
+func (s Style) Wrap(str string) *Format {
+	return s.wrap(func(*Terminal, Level) string {
+		return str
+	})
+}
+
+// Sprint formats using the default formats for a, like fmt.Sprint, and then
+// wraps the result with s. It will expand each Format in a appropriately.
+// The arguments that are not Formats are formatted right away.
+func (s Style) Sprint(a ...interface{}) *Format {
+	// Formats expand to strings, so fmt.Sprint never adds spaces around them
+	// and the arguments between them can be formatted on their own.
+	var args []interface{}
+	run := 0
+	for i, v := range a {
+		if _, ok := v.(*Format); ok {
+			if run < i {
+				args = append(args, fmt.Sprint(a[run:i]...))
+			}
+			args = append(args, v)
+			run = i + 1
+		}
+	}
+	if run < len(a) {
+		args = append(args, fmt.Sprint(a[run:]...))
+	}
+	return s.wrap(func(t *Terminal, l Level) string {
+		return fmt.Sprint(ExpandFormatsTerminal(t, l, args)...)
+	})
+}
+
+// Sprintf processes the highlight verbs in format and then formats a like
+// fmt.Sprintf. It then wraps the result with s.
+// It will expand each Format in a appropriately.
+// The arguments that are not Formats are formatted right away.
+func (s Style) Sprintf(format string, a ...interface{}) *Format {
+	f := Prepare(format)
+	a = freezeArgs(f.GetLevel(None), a)
+	return s.wrap(func(t *Terminal, l Level) string {
+		return fmt.Sprintf(f.GetTerminal(t, l), ExpandFormatsTerminal(t, l, a)...)
+	})
+}
+
+// wrap returns a Format with the strings rendered by render wrapped by the attributes of s.
+func (s Style) wrap(render func(*Terminal, Level) string) *Format {
+	attrs := s.attrs()
+	if attrs == "" {
+		return newFormat(defaultTerminal, render)
+	}
+	return newFormat(defaultTerminal, func(t *Terminal, l Level) string {
+		return t.Run("%h["+attrs+"]", l) + render(t, l) + t.Run("%r", l)
+	})
+}
+
+// AttrError describes an invalid list of attributes.
+type AttrError struct {
+	Attrs string // the attributes, e.g. "fgRed+bold"
+	Err   string // the error as written into highlighted strings, e.g. "%!h(BADATTR)"
+}
+
+func (e *AttrError) Error() string {
+	return "color: invalid attributes " + strconv.Quote(e.Attrs) + ": " + e.Err
+}
+
+// styles holds the registered styles.
+var styles = struct {
+	sync.RWMutex
+	m   map[string]string // style names to their attributes
+	gen uint64            // incremented whenever a style changes
+}{m: make(map[string]string)}
+
+// DefineStyle registers attrs, a + separated list of attributes like "fgRed+bold",
+// under name. The style can then be used in highlight verbs as %h[@name], or as
+// %h[name] if name is not also the name of a mode or color.
+// Defining a style that already exists replaces it, which makes it easy to switch
+// themes at runtime. Formats that were already prepared pick up the new attributes
+// the next time they are used.
+// Styles cannot refer to other styles.
+// If attrs is invalid, an *AttrError is returned.
+func DefineStyle(name, attrs string) error {
+	if !validStyleName(name) {
+		return fmt.Errorf("color: invalid style name %q", name)
+	}
+	if err := validateStyle(attrs); err != nil {
+		return err
+	}
+	styles.Lock()
+	styles.m[name] = attrs
+	styles.gen++
+	styles.Unlock()
+	return nil
+}
+
+// validStyleName reports whether name can be used in highlight verbs.
+func validStyleName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "@+[]%/ \t")
+}
+
+// lookupStyle returns the attributes of the style name.
+func lookupStyle(name string) (attrs string, ok bool) {
+	styles.RLock()
+	attrs, ok = styles.m[name]
+	styles.RUnlock()
+	return attrs, ok
+}
+
+// stylesGen returns the generation of the registered styles.
+func stylesGen() uint64 {
+	styles.RLock()
+	defer styles.RUnlock()
+	return styles.gen
+}
+
+// validateStyle returns an *AttrError if attrs is not a valid list of style attributes.
+func validateStyle(attrs string) error {
+	// These would end the verb early, start another one or pop a scope.
+	if strings.ContainsAny(attrs, "]%/") {
+		// Unescape the leading %.
+		return &AttrError{attrs, errInvalid[1:]}
+	}
+	hl := newStyleHighlighter(attrs, defaultTerminal, None)
+	defer hl.free()
+	hl.run()
+	if hl.err != "" {
+		// Unescape the leading %.
+		return &AttrError{attrs, hl.err[1:]}
+	}
+	return nil
+}
+
+// runStyle returns the control sequences for the attributes of a style.
+func runStyle(attrs string, t *Terminal, l Level) string {
+	hl := newStyleHighlighter(attrs, t, l)
+	defer hl.free()
+	return hl.run()
+}
+
+// newStyleHighlighter returns a highlighter that scans attrs as the attributes of a style.
+func newStyleHighlighter(attrs string, t *Terminal, l Level) *highlighter {
+	hl := newHighlighter("%h["+attrs+"]", t, l)
+	hl.style = true
+	return hl
+}
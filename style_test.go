@@ -0,0 +1,170 @@
+package color
+
+import "testing"
+
+func TestDefineStyle(t *testing.T) {
+	t.Parallel()
+	if err := DefineStyle("testError", "fgRed+bold"); err != nil {
+		t.Fatal(err)
+	}
+	exp := Highlight("%h[fgRed+bold]hi")
+	if r := Highlight("%h[@testError]hi"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := Highlight("%h[testError]hi"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = Highlight("%h[underline+fgRed+bold+fg23]hi")
+	if r := Highlight("%h[underline+@testError+fg23]hi"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := Strip("%h[@testError]hi"); r != "hi" {
+		t.Errorf("Expected %q but result was %q", "hi", r)
+	}
+}
+
+func TestRedefineStyle(t *testing.T) {
+	t.Parallel()
+	if err := DefineStyle("testPath", "fgBlue"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DefineStyle("testPath", "fg33+underline"); err != nil {
+		t.Fatal(err)
+	}
+	exp := Highlight("%h[fg33+underline]hi")
+	if r := Highlight("%h[@testPath]hi"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestColorLikeStyles(t *testing.T) {
+	t.Parallel()
+	exp := Highlight("%h[bold]hi")
+	for _, name := range []string{"bgTestJob", "ulTestCer", "fg1TestX", "fg#TestX"} {
+		if err := DefineStyle(name, "bold"); err != nil {
+			t.Fatal(err)
+		}
+		if r := Highlight("%h[" + name + "]hi"); r != exp {
+			t.Errorf("Expected %q from %q but result was %q", exp, name, r)
+		}
+	}
+}
+
+var styleEdgeCases = map[string]string{
+	"%h[@testMissing]":      errBadAttr,
+	"%h[testMissing]":       errBadAttr,
+	"%h[@]":                 errBadAttr,
+	"%h[fgRed+@testMissing": exp(tiColor(1, -1)) + errShort,
+}
+
+func TestStyleEdgeCases(t *testing.T) {
+	t.Parallel()
+	for k, v := range styleEdgeCases {
+		if r := Highlight(k); r != v {
+			t.Errorf("Expected %q from %q but result was %q", v, k, r)
+		}
+	}
+}
+
+var invalidStyles = map[string]string{
+	"fgRed+lold":   "%!h(BADATTR)",
+	"":             "%!h(MISSING)",
+	"fgRed+":       "%!h(SHORT)",
+	"fg#ff":        "%!h(BADATTR)",
+	"@testNested":  "%!h(BADATTR)",
+	"fgRed]junk%s": "%!h(INVALID)",
+	"bold+%s":      "%!h(INVALID)",
+	"/":            "%!h(INVALID)",
+}
+
+func TestDefineStyleErrors(t *testing.T) {
+	t.Parallel()
+	if err := DefineStyle("testNested", "bold"); err != nil {
+		t.Fatal(err)
+	}
+	for attrs, exp := range invalidStyles {
+		err := DefineStyle("testInvalid", attrs)
+		aerr, ok := err.(*AttrError)
+		if !ok {
+			t.Errorf("Expected an *AttrError from %q but result was %v", attrs, err)
+			continue
+		}
+		if aerr.Err != exp {
+			t.Errorf("Expected %q from %q but result was %q", exp, attrs, aerr.Err)
+		}
+	}
+	for _, name := range []string{"", "a+b", "@a", "a]", "/", "a/b"} {
+		if err := DefineStyle(name, "bold"); err == nil {
+			t.Errorf("Expected an error from the style name %q", name)
+		}
+	}
+}
+
+var typedStyles = map[string]Style{
+	"fgRed+bold":                   {FG: Red, Bold: true},
+	"bgBrightCyan":                 {BG: BrightCyan},
+	"fg23+bg235+underline+reverse": {FG: Color256(23), BG: Color256(235), Underline: true, Reverse: true},
+	"fg#010203+bg#ff8800+blink":    {FG: RGB(1, 2, 3), BG: RGB(255, 136, 0), Blink: true},
+	"fgBlack+dim":                  {FG: Color256(0), Dim: true},
+	"italic+invisible+standout":    {Italic: true, Invisible: true, Standout: true},
+	"strike+doubleunderline":       {Strike: true, DoubleUnderline: true},
+	"curlyunderline+overline":      {CurlyUnderline: true, Overline: true},
+	"fgDefault+bgDefault":          {FG: Default, BG: Default},
+	"ulRed+curlyunderline":         {UL: Red, CurlyUnderline: true},
+	"ul#ff0000+underline":          {UL: RGB(255, 0, 0), Underline: true},
+}
+
+func TestStyleWrap(t *testing.T) {
+	t.Parallel()
+	for attrs, s := range typedStyles {
+		f := s.Wrap("hi")
+		exp := Highlight("%h[" + attrs + "]hi%r")
+		if r := f.Get(true); r != exp {
+			t.Errorf("Expected %q from %+v but result was %q", exp, s, r)
+		}
+		if r := f.Get(false); r != "hi" {
+			t.Errorf("Expected %q from %+v but result was %q", "hi", s, r)
+		}
+	}
+	if r := (Style{}).Wrap("hi").Get(true); r != "hi" {
+		t.Errorf("Expected %q but result was %q", "hi", r)
+	}
+}
+
+func TestStyleSprint(t *testing.T) {
+	t.Parallel()
+	s := Style{FG: Red, Bold: true}
+	inner := Prepare("%h[fgGreen]rip")
+	a := []interface{}{"foo", inner, 3}
+	f := s.Sprint(a...)
+	exp := Highlight("%h[fgRed+bold]foo") + inner.Get(true) + "3" + Highlight("%r")
+	if r := f.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := f.Get(false); r != "foorip3" {
+		t.Errorf("Expected %q but result was %q", "foorip3", r)
+	}
+	if a[1] != inner {
+		t.Errorf("Expected the arguments to be left unchanged but result was %v", a)
+	}
+	nums := []int{1}
+	f = s.Sprint("foo", 3, nums, inner, nums)
+	nums[0] = 2
+	exp = Highlight("%h[fgRed+bold]foo3 [1]") + inner.Get(true) + "[1]" + Highlight("%r")
+	if r := f.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	f = s.Sprintf("%h[underline]%s: %d", inner, 3)
+	exp = Highlight("%h[fgRed+bold]%h[underline]") + inner.Get(true) + ": 3" + Highlight("%r")
+	if r := f.Get(true); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := f.Get(false); r != "rip: 3" {
+		t.Errorf("Expected %q but result was %q", "rip: 3", r)
+	}
+	f = s.Sprintf("%v", nums)
+	nums[0] = 3
+	if r := f.Get(false); r != "[2]" {
+		t.Errorf("Expected %q but result was %q", "[2]", r)
+	}
+}
@@ -0,0 +1,24 @@
+package table_test
+
+import (
+	"os"
+
+	"github.com/nhooyr/color"
+	"github.com/nhooyr/color/table"
+)
+
+func Example() {
+	t := table.New(
+		table.Column{Header: "NAME", Style: "fgCyan"},
+		table.Column{Header: "STATUS"},
+		table.Column{Header: "RESTARTS", Align: table.Right},
+		table.Column{Header: "MESSAGE", MaxWidth: 20},
+	)
+	t.SetBorder(table.Unicode)
+	t.AddRow("api", "%h[fgGreen]running", 0, "")
+	t.AddRow("worker", color.Prepare("%h[fgRed+bold]failed"), 12, "connection refused by upstream")
+
+	// Aligned even though some cells are colored and the last message is truncated
+	// with "…". If standard output is not a terminal, the colors are stripped.
+	t.Print(color.NewLevel(os.Stdout, color.Detect(os.Stdout)))
+}
@@ -0,0 +1,274 @@
+/*
+Package table renders aligned tables whose cells may contain highlight verbs.
+
+Column widths are computed from the visible width of the cells, so colored cells
+line up with plain ones. Tables are written through a *color.Printer, so they are
+stripped cleanly when the Printer has color disabled.
+*/
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/nhooyr/color"
+)
+
+// Align is the alignment of the cells in a column.
+type Align int
+
+// Alignments.
+const (
+	Left Align = iota
+	Right
+	Center
+)
+
+// Column describes a column of a table.
+type Column struct {
+	Header   string // header cell, may contain highlight verbs
+	Align    Align  // alignment of the cells
+	MaxWidth int    // cells wider than MaxWidth are truncated, 0 means no limit
+	Style    string // attributes applied to each cell, e.g. "fgCyan+bold"
+}
+
+// Border is the set of strings used to draw the lines of a table.
+type Border struct {
+	H, V                               string // horizontal and vertical lines
+	TopLeft, TopMid, TopRight          string // corners and joints of the top line
+	MidLeft, MidMid, MidRight          string // corners and joints of the line below the header
+	BottomLeft, BottomMid, BottomRight string // corners and joints of the bottom line
+}
+
+// Borders.
+var (
+	// NoBorder draws no lines and separates columns with two spaces.
+	NoBorder = Border{}
+
+	// ASCII draws lines with -, | and +.
+	ASCII = Border{
+		H: "-", V: "|",
+		TopLeft: "+", TopMid: "+", TopRight: "+",
+		MidLeft: "+", MidMid: "+", MidRight: "+",
+		BottomLeft: "+", BottomMid: "+", BottomRight: "+",
+	}
+
+	// Unicode draws lines with box drawing characters.
+	Unicode = Border{
+		H: "─", V: "│",
+		TopLeft: "┌", TopMid: "┬", TopRight: "┐",
+		MidLeft: "├", MidMid: "┼", MidRight: "┤",
+		BottomLeft: "└", BottomMid: "┴", BottomRight: "┘",
+	}
+)
+
+// Table is a table of cells that are aligned when printed.
+type Table struct {
+	cols        []Column
+	rows        [][]interface{}
+	border      Border
+	ellipsis    string // replaces the end of truncated cells
+	headerStyle string // attributes applied to the header cells
+}
+
+// New creates a new Table with the columns cols.
+// It has no border, truncates cells with "…" and prints the headers in bold.
+func New(cols ...Column) *Table {
+	return &Table{
+		cols:        cols,
+		border:      NoBorder,
+		ellipsis:    "…",
+		headerStyle: "bold",
+	}
+}
+
+// SetBorder sets the border of t.
+func (t *Table) SetBorder(b Border) {
+	t.border = b
+}
+
+// SetEllipsis sets the string that replaces the end of truncated cells.
+func (t *Table) SetEllipsis(s string) {
+	t.ellipsis = s
+}
+
+// SetHeaderStyle sets the attributes applied to the header cells, e.g. "bold+underline".
+// An empty string applies none.
+func (t *Table) SetHeaderStyle(attrs string) {
+	t.headerStyle = attrs
+}
+
+// AddRow appends a row of cells to t. The highlight verbs in a string cell are processed
+// and a *color.Format cell is expanded. Both are printed like format strings without
+// arguments, so %% is printed as %, but other verbs are printed as is.
+// Any other cell is formatted with fmt.Sprint.
+// If the row has more cells than t has columns, columns without headers are added.
+func (t *Table) AddRow(cells ...interface{}) {
+	for len(t.cols) < len(cells) {
+		t.cols = append(t.cols, Column{})
+	}
+	t.rows = append(t.rows, cells)
+}
+
+// Print writes t to p. The cells are rendered for the terminal and level of color support
+// of p. It returns the number of bytes written and any write error encountered.
+func (t *Table) Print(p *color.Printer) (n int, err error) {
+	r := renderer{t: t, term: p.Terminal(), level: p.Level()}
+	return p.Print(r.render())
+}
+
+// renderer renders a table for a terminal and level of color support.
+type renderer struct {
+	t     *Table
+	term  *color.Terminal
+	level color.Level
+	buf   bytes.Buffer
+}
+
+// render returns the rendered table.
+func (r *renderer) render() string {
+	header := r.header()
+	rows := make([][]string, len(r.t.rows))
+	for i, row := range r.t.rows {
+		rows[i] = make([]string, len(r.t.cols))
+		for j, c := range row {
+			rows[i][j] = r.cell(c)
+		}
+	}
+	widths := make([]int, len(r.t.cols))
+	for _, row := range append([][]string{header}, rows...) {
+		if row == nil {
+			continue
+		}
+		for j, s := range row {
+			if w := color.VisibleWidth(s); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+	for j, col := range r.t.cols {
+		if col.MaxWidth > 0 && widths[j] > col.MaxWidth {
+			widths[j] = col.MaxWidth
+		}
+	}
+
+	b := r.t.border
+	r.line(widths, b.TopLeft, b.TopMid, b.TopRight)
+	if header != nil {
+		r.row(widths, header, r.t.headerStyle, true)
+		r.line(widths, b.MidLeft, b.MidMid, b.MidRight)
+	}
+	for _, row := range rows {
+		r.row(widths, row, "", false)
+	}
+	r.line(widths, b.BottomLeft, b.BottomMid, b.BottomRight)
+	return r.buf.String()
+}
+
+// header returns the rendered header cells, or nil if no column has a header.
+func (r *renderer) header() []string {
+	var header []string
+	for j, col := range r.t.cols {
+		if col.Header == "" {
+			continue
+		}
+		if header == nil {
+			header = make([]string, len(r.t.cols))
+		}
+		header[j] = r.cell(col.Header)
+	}
+	return header
+}
+
+// cell returns the rendered string of the cell c.
+func (r *renderer) cell(c interface{}) string {
+	var s string
+	switch c := c.(type) {
+	case string:
+		s = r.term.Run(c, r.level)
+	case *color.Format:
+		s = c.GetTerminal(r.term, r.level)
+	default:
+		return fmt.Sprint(c)
+	}
+	// Both are format strings, so undo their escaping of '%'.
+	return strings.Replace(s, "%%", "%", -1)
+}
+
+// line writes a horizontal line of the border with the given corners and joints.
+func (r *renderer) line(widths []int, left, mid, right string) {
+	h := r.t.border.H
+	if h == "" {
+		return
+	}
+	r.buf.WriteString(left)
+	for j, w := range widths {
+		if j > 0 {
+			r.buf.WriteString(mid)
+		}
+		r.buf.WriteString(strings.Repeat(h, w+2))
+	}
+	r.buf.WriteString(right)
+	r.buf.WriteByte('\n')
+}
+
+// row writes a row of cells. The header row uses the alignment of its columns
+// but not their styles.
+func (r *renderer) row(widths []int, cells []string, style string, header bool) {
+	start := r.buf.Len()
+	v := r.t.border.V
+	if v != "" {
+		r.buf.WriteString(v)
+	}
+	for j, w := range widths {
+		if j > 0 {
+			if v == "" {
+				r.buf.WriteString("  ")
+			} else {
+				r.buf.WriteString(v)
+			}
+		}
+		col := r.t.cols[j]
+		s := color.Truncate(cells[j], w, r.t.ellipsis)
+		// Do not let the attributes of a cell spill into the rest of the table.
+		sgr, link := color.ActiveAttributes(s)
+		if link {
+			s += r.term.Run("%L", r.level)
+		}
+		if sgr {
+			s += r.term.Run("%r", r.level)
+		}
+		if !header {
+			style = col.Style
+		}
+		if style != "" {
+			s = r.term.Run("%h["+style+"]", r.level) + s + r.term.Run("%r", r.level)
+		}
+		if v != "" {
+			r.buf.WriteByte(' ')
+		}
+		r.buf.WriteString(pad(s, w, col.Align))
+		if v != "" {
+			r.buf.WriteByte(' ')
+		}
+	}
+	if v != "" {
+		r.buf.WriteString(v)
+	} else {
+		// Do not end lines with the padding of the last columns.
+		r.buf.Truncate(start + len(bytes.TrimRight(r.buf.Bytes()[start:], " ")))
+	}
+	r.buf.WriteByte('\n')
+}
+
+// pad pads s with spaces to w columns according to a.
+func pad(s string, w int, a Align) string {
+	switch a {
+	case Right:
+		return color.PadLeft(s, w)
+	case Center:
+		return color.PadCenter(s, w)
+	}
+	return color.PadRight(s, w)
+}
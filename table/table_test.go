@@ -0,0 +1,155 @@
+package table
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nhooyr/color"
+)
+
+func render(t *Table, c bool) string {
+	var b bytes.Buffer
+	t.Print(color.New(&b, c))
+	return b.String()
+}
+
+func TestNoBorder(t *testing.T) {
+	t.Parallel()
+	tb := New(
+		Column{Header: "NAME"},
+		Column{Header: "%h[fgRed]STATUS", Align: Right},
+		Column{Header: "AGE", Align: Center},
+	)
+	tb.AddRow("api", "%h[fgGreen]running%r", 3)
+	tb.AddRow(color.Prepare("%h[bold]worker"), "failed", 12)
+	exp := "NAME     STATUS  AGE\n" +
+		"api     running   3\n" +
+		"worker   failed  12\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestBorders(t *testing.T) {
+	t.Parallel()
+	tb := New(Column{Header: "K"}, Column{Header: "VALUE", Align: Right})
+	tb.AddRow("a", 1)
+	tb.SetBorder(ASCII)
+	exp := "+---+-------+\n" +
+		"| K | VALUE |\n" +
+		"+---+-------+\n" +
+		"| a |     1 |\n" +
+		"+---+-------+\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	tb.SetBorder(Unicode)
+	exp = "┌───┬───────┐\n" +
+		"│ K │ VALUE │\n" +
+		"├───┼───────┤\n" +
+		"│ a │     1 │\n" +
+		"└───┴───────┘\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	t.Parallel()
+	tb := New(Column{MaxWidth: 5}, Column{})
+	tb.AddRow("%h[fgRed]abcdefgh", "x")
+	tb.AddRow("日本語です", "y")
+	exp := "abcd…  x\n" +
+		"日本…  y\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	tb.SetEllipsis("")
+	exp = "abcde  x\n" +
+		"日本   y\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestColoredAlignment(t *testing.T) {
+	t.Parallel()
+	tb := New(Column{Header: "NAME", Style: "fgCyan"}, Column{Header: "STATUS"})
+	tb.SetBorder(Unicode)
+	tb.AddRow("api", "%h[fgGreen]running%r")
+	tb.AddRow("日本", color.Prepare("%h[fgRed+bold]failed%r"))
+	tb.AddRow("worker", "ok")
+	colored := render(tb, true)
+	if colored == render(tb, false) {
+		t.Skip("The terminal does not support color")
+	}
+	lines := strings.Split(strings.TrimSuffix(colored, "\n"), "\n")
+	for _, l := range lines[1:] {
+		if w, exp := color.VisibleWidth(l), color.VisibleWidth(lines[0]); w != exp {
+			t.Errorf("Expected %q to be %d columns wide but it was %d", l, exp, w)
+		}
+	}
+	if !strings.Contains(colored, color.Highlight("%h[fgCyan]")+"api") {
+		t.Errorf("Expected the column style in %q", colored)
+	}
+}
+
+func TestExtraCells(t *testing.T) {
+	t.Parallel()
+	tb := New()
+	tb.AddRow("a", "b")
+	tb.AddRow("ccc")
+	exp := "a    b\n" +
+		"ccc\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestOpenCells(t *testing.T) {
+	t.Parallel()
+	tb := New(Column{}, Column{})
+	tb.SetBorder(ASCII)
+	tb.AddRow("%h[fgGreen]running", color.Prepare("%l[http://x]%h[fgRed+bold]failed"))
+	var b bytes.Buffer
+	tb.Print(color.NewLevel(&b, color.Ansi256))
+	lines := strings.Split(b.String(), "\n")
+	for _, l := range lines {
+		if sgr, link := color.ActiveAttributes(l); sgr || link {
+			t.Errorf("Expected no active attributes at the end of %q", l)
+		}
+	}
+	first := lines[1][:strings.Index(lines[1], " |")]
+	if sgr, _ := color.ActiveAttributes(first); sgr {
+		t.Errorf("Expected the first cell of %q to be closed", lines[1])
+	}
+}
+
+func TestPercentCells(t *testing.T) {
+	t.Parallel()
+	tb := New(Column{Header: "100%%"})
+	tb.AddRow("%l[http://x/a%20b]x%L")
+	tb.AddRow("50%")
+	tb.AddRow("50%% %d")
+	tb.AddRow(color.Prepare("%h[bold]50%% done"))
+	tb.AddRow(color.Prepare("%l[http://x/%20]f%L"))
+	exp := "100%\n" +
+		"x\n" +
+		"50%\n" +
+		"50% %d\n" +
+		"50% done\n" +
+		"f\n"
+	if r := render(tb, false); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	colored := render(tb, true)
+	if strings.Contains(colored, "%%") {
+		t.Errorf("Expected no escaped %% in %q", colored)
+	}
+	for _, target := range []string{"http://x/a%20b", "http://x/%20"} {
+		if colored != exp && !strings.Contains(colored, "\x1b]8;;"+target+"\x1b\\") {
+			t.Errorf("Expected the link target %q in %q", target, colored)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package color
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nhooyr/terminfo"
+	"github.com/nhooyr/terminfo/caps"
+)
+
+// Terminal describes the control sequences and color support of a terminal.
+// Attach one to a Printer, Logger or Format to produce the correct sequences for a
+// terminal other than the one described by the environment, e.g. when rendering
+// output for many remote clients with different TERMs.
+type Terminal struct {
+	ti        *terminfo.Terminfo // nil if the terminal does not support any attributes
+	maxColors int                // number of colors in the palette
+	trueColor bool               // whether 24-bit colors are supported
+	ulColor   bool               // whether colored underlines are supported
+}
+
+// LoadTerminal loads the terminfo entry for the terminal name, e.g. xterm-256color.
+func LoadTerminal(name string) (*Terminal, error) {
+	ti, err := terminfo.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return newTerminal(ti, false), nil
+}
+
+// LoadTerminalFile loads the compiled terminfo entry in the file at path.
+func LoadTerminalFile(path string) (*Terminal, error) {
+	ti, err := terminfo.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newTerminal(ti, false), nil
+}
+
+// LoadTerminalEnv loads the terminfo entry for the terminal named by the TERM
+// environment variable. The terminal also supports 24-bit colors if the COLORTERM
+// environment variable is truecolor or 24bit.
+func LoadTerminalEnv() (*Terminal, error) {
+	ti, err := terminfo.LoadEnv()
+	if err != nil {
+		return nil, err
+	}
+	return newTerminal(ti, colorTerm()), nil
+}
+
+// colorTerm reports whether the COLORTERM environment variable advertises 24-bit colors.
+func colorTerm() bool {
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return true
+	}
+	return false
+}
+
+// newTerminal returns a Terminal for ti. The trueColor argument forces support
+// for 24-bit colors.
+func newTerminal(ti *terminfo.Terminfo, trueColor bool) *Terminal {
+	return &Terminal{
+		ti:        ti,
+		maxColors: int(ti.Numbers[caps.MaxColors]),
+		trueColor: trueColor || ti.ExtBools["RGB"] || ti.ExtBools["Tc"],
+		ulColor:   ti.ExtStrings["Setulc"] != "",
+	}
+}
+
+// defaultTerminal is the terminal described by the environment.
+// If its terminfo entry cannot be loaded, the built-in terminal is used instead.
+var defaultTerminal = loadDefaultTerminal()
+
+func loadDefaultTerminal() *Terminal {
+	t, err := LoadTerminalEnv()
+	if err != nil {
+		t = newTerminal(builtin, colorTerm())
+	}
+	if os.Getenv("FORCE_COLOR") == "3" {
+		t.trueColor = true
+	}
+	return t
+}
+
+// DefaultTerminal returns the terminal described by the TERM and COLORTERM environment
+// variables, or the one returned by BuiltinTerminal if the terminfo entry of TERM cannot
+// be loaded. It is used by everything in this package that does not take a Terminal.
+// It also supports 24-bit colors if FORCE_COLOR is 3, as Detect then reports TrueColor.
+func DefaultTerminal() *Terminal {
+	return defaultTerminal
+}
+
+// Level returns the highest level of color support of t.
+// A terminal without colors, like vt100, is still Basic16 so that its other attributes,
+// such as bold and underline, are written. No colors are written for it.
+func (t *Terminal) Level() Level {
+	switch {
+	case t.ti == nil:
+		return None
+	case t.trueColor:
+		return TrueColor
+	case t.maxColors >= 256:
+		return Ansi256
+	}
+	return Basic16
+}
+
+// Run is the same as RunLevel but produces the control sequences for t.
+func (t *Terminal) Run(s string, l Level) string {
+	hl := newHighlighter(s, t, l)
+	defer hl.free()
+	return hl.run()
+}
+
+// Prepare is the same as the Prepare function but produces the control sequences for t.
+func (t *Terminal) Prepare(f string) *Format {
+	return newCompiledFormat(t, f)
+}
+
+// str returns the string capability n, or an empty string if t lacks it.
+func (t *Terminal) str(n int16) string {
+	if t.ti == nil {
+		return ""
+	}
+	return t.ti.Strings[n]
+}
+
+// color returns the control sequence that sets the foreground and background
+// to the palette colors fg and bg. Either can be -1 to leave it unchanged.
+func (t *Terminal) color(fg, bg int) string {
+	if t.ti == nil {
+		return ""
+	}
+	return t.ti.Color(fg, bg)
+}
+
+// extString returns the string of the extended capability of m, with the
+// parameter substituted. It returns an empty string if t lacks it.
+func (t *Terminal) extString(m extMode) string {
+	if t.ti == nil {
+		return ""
+	}
+	s := t.ti.ExtStrings[m.name]
+	if m.param == 0 {
+		return s
+	}
+	return strings.Replace(s, "%p1%d", strconv.Itoa(m.param), 1)
+}
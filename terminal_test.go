@@ -0,0 +1,146 @@
+package color
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/nhooyr/terminfo"
+	"github.com/nhooyr/terminfo/caps"
+)
+
+func loadTerminal(t *testing.T, name string) *Terminal {
+	term, err := LoadTerminal(name)
+	if err != nil {
+		t.Skipf("Cannot load the terminfo for %q: %v", name, err)
+	}
+	return term
+}
+
+var terminalLevels = map[string]Level{
+	"xterm-256color": Ansi256,
+	"linux":          Basic16,
+	"xterm-direct":   TrueColor,
+}
+
+func TestTerminalLevel(t *testing.T) {
+	t.Parallel()
+	for name, l := range terminalLevels {
+		term, err := LoadTerminal(name)
+		if err != nil {
+			continue
+		}
+		if r := term.Level(); r != l {
+			t.Errorf("Expected %v from %q but result was %v", l, name, r)
+		}
+	}
+	if r := new(Terminal).Level(); r != None {
+		t.Errorf("Expected %v but result was %v", None, r)
+	}
+}
+
+func TestTerminalRun(t *testing.T) {
+	t.Parallel()
+	xterm := loadTerminal(t, "xterm-256color")
+	linux := loadTerminal(t, "linux")
+	exp := xterm.ti.Color(196, -1) + xterm.ti.Strings[caps.EnterBoldMode] + "hi"
+	if r := xterm.Run("%h[fg196+bold]hi", Ansi256); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = linux.ti.Color(fitPaletteColor(196, linux.maxColors), -1) + linux.ti.Strings[caps.EnterBoldMode] + "hi"
+	if r := linux.Run("%h[fg196+bold]hi", Ansi256); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = linux.ti.Color(fitColor(255, 136, 0, linux.maxColors), -1) + "hi"
+	if r := linux.Run("%h[fg#ff8800]hi", TrueColor); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := new(Terminal).Run("%h[fg196+bold]hi", TrueColor); r != "hi" {
+		t.Errorf("Expected %q but result was %q", "hi", r)
+	}
+}
+
+func TestTerminalWithoutColors(t *testing.T) {
+	t.Parallel()
+	term := newTerminal(&terminfo.Terminfo{
+		Strings: [caps.StringCount]string{
+			caps.ExitAttributeMode: "\x1b[m",
+			caps.EnterBoldMode:     "\x1b[1m",
+		},
+	}, false)
+	if r := term.Level(); r != Basic16 {
+		t.Errorf("Expected %v but result was %v", Basic16, r)
+	}
+	exp := "\x1b[1mx\x1b[m"
+	if r := term.Run("%h[bold+fgRed+bg#ff8800]x%r", term.Level()); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestTerminalUnderlineColors(t *testing.T) {
+	t.Parallel()
+	kitty := loadTerminal(t, "kitty")
+	if !kitty.ulColor {
+		t.Skip("kitty does not support colored underlines")
+	}
+	tests := map[string]string{
+		"%h[ul196]hi":     "\x1b[58;5;" + strconv.Itoa(fitPaletteColor(196, 16)) + "mhi",
+		"%h[ul#ff0000]hi": "\x1b[58;5;" + strconv.Itoa(fitColor(255, 0, 0, 16)) + "mhi",
+	}
+	for s, exp := range tests {
+		if r := kitty.Run(s, Basic16); r != exp {
+			t.Errorf("Expected %q from %q but result was %q", exp, s, r)
+		}
+	}
+}
+
+func TestTerminalFormat(t *testing.T) {
+	t.Parallel()
+	xterm := loadTerminal(t, "xterm-256color")
+	linux := loadTerminal(t, "linux")
+	f := xterm.Prepare("%h[fgRed+underline]%s%r")
+	for _, term := range []*Terminal{xterm, linux} {
+		exp := term.Run("%h[fgRed+underline]%s%r", Basic16)
+		if r := f.GetTerminal(term, Basic16); r != exp {
+			t.Errorf("Expected %q but result was %q", exp, r)
+		}
+	}
+	if r := f.GetLevel(Basic16); r != f.GetTerminal(xterm, Basic16) {
+		t.Errorf("Expected %q but result was %q", f.GetTerminal(xterm, Basic16), r)
+	}
+}
+
+func TestNewTerminal(t *testing.T) {
+	t.Parallel()
+	linux := loadTerminal(t, "linux")
+	var buf bytes.Buffer
+	p := NewTerminal(&buf, linux, Basic16)
+	inner := Prepare("%h[fgGreen]rip%r")
+	p.Printf("%h[bold]%s: %v", "foo", inner)
+	exp := linux.Run("%h[bold]foo: ", Basic16) + linux.Run("%h[fgGreen]rip%r", Basic16)
+	if r := buf.String(); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if p.Terminal() != linux {
+		t.Errorf("Expected the terminal to be %p but result was %p", linux, p.Terminal())
+	}
+}
+
+func TestBuiltinTerminal(t *testing.T) {
+	t.Parallel()
+	term := BuiltinTerminal()
+	if r := term.Level(); r != Ansi256 {
+		t.Errorf("Expected %v but result was %v", Ansi256, r)
+	}
+	exp := "\x1b[1m\x1b[4mhi\x1b(B\x1b[m"
+	if r := term.Run("%h[bold+underline]hi%r", Ansi256); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = builtin.Color(fitPaletteColor(196, 16), -1) + "hi"
+	if r := term.Run("%h[fg196]hi", Basic16); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	if r := term.Run("%h[fg196]hi", None); r != "hi" {
+		t.Errorf("Expected %q but result was %q", "hi", r)
+	}
+}
@@ -0,0 +1,97 @@
+package color
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Theme is a set of named styles, usually loaded from a file with LoadTheme.
+type Theme struct {
+	styles map[string]string // style names to their attributes
+	names  []string          // style names in the order they were defined
+}
+
+// ThemeError describes an invalid line in a theme.
+type ThemeError struct {
+	Line int    // line number, starting at 1
+	Name string // name of the style, if the line has one
+	Err  error  // either an *AttrError or a syntax error
+}
+
+func (e *ThemeError) Error() string {
+	if aerr, ok := e.Err.(*AttrError); ok {
+		return fmt.Sprintf("color: theme line %d: invalid attributes %q for style %q: %s", e.Line, aerr.Attrs, e.Name, aerr.Err)
+	}
+	return fmt.Sprintf("color: theme line %d: %v", e.Line, e.Err)
+}
+
+// Syntax errors in themes.
+var (
+	errNoEquals    = errors.New("expected name = attributes")
+	errInvalidName = errors.New("invalid style name")
+)
+
+// LoadTheme reads a theme from r. Each line of a theme defines a style as a name,
+// an equals sign and then the attributes in the same syntax as the highlight verb.
+// Blank lines and lines beginning with # are ignored.
+//
+//	# My colors.
+//	error = fgRed+bold
+//	path  = fg33+underline
+//
+// If a style is defined more than once, the last definition is used.
+// The first invalid line is returned as a *ThemeError.
+func LoadTheme(r io.Reader) (*Theme, error) {
+	t := &Theme{styles: make(map[string]string)}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(l, '=')
+		if i < 0 {
+			return nil, &ThemeError{Line: line, Err: errNoEquals}
+		}
+		name, attrs := strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])
+		if !validStyleName(name) {
+			return nil, &ThemeError{Line: line, Name: name, Err: errInvalidName}
+		}
+		if err := validateStyle(attrs); err != nil {
+			return nil, &ThemeError{line, name, err}
+		}
+		if _, ok := t.styles[name]; !ok {
+			t.names = append(t.names, name)
+		}
+		t.styles[name] = attrs
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Style returns the attributes of the style name.
+func (t *Theme) Style(name string) (attrs string, ok bool) {
+	attrs, ok = t.styles[name]
+	return attrs, ok
+}
+
+// Names returns the names of the styles in t in the order they were defined.
+func (t *Theme) Names() []string {
+	return append([]string(nil), t.names...)
+}
+
+// Use defines each style in t with DefineStyle, replacing any existing
+// styles with the same names.
+func (t *Theme) Use() {
+	styles.Lock()
+	for name, attrs := range t.styles {
+		styles.m[name] = attrs
+	}
+	styles.gen++
+	styles.Unlock()
+}
@@ -0,0 +1,82 @@
+package color
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const theme = `# Test theme.
+testThemeError = fgRed+bold
+
+testThemePath  =  fg33+underline
+	testThemeRGB=bg#ff8800
+testThemeError = fgMagenta
+`
+
+func TestLoadTheme(t *testing.T) {
+	t.Parallel()
+	th, err := LoadTheme(strings.NewReader(theme))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"testThemeError", "testThemePath", "testThemeRGB"}
+	if r := th.Names(); !reflect.DeepEqual(r, exp) {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	styles := map[string]string{
+		"testThemeError": "fgMagenta",
+		"testThemePath":  "fg33+underline",
+		"testThemeRGB":   "bg#ff8800",
+	}
+	for name, exp := range styles {
+		if r, ok := th.Style(name); !ok || r != exp {
+			t.Errorf("Expected %q for %q but result was %q", exp, name, r)
+		}
+	}
+	if _, ok := th.Style("testThemeMissing"); ok {
+		t.Errorf("Expected %q to be missing", "testThemeMissing")
+	}
+	th.Use()
+	for name, attrs := range styles {
+		exp := Highlight("%h[" + attrs + "]hi")
+		if r := Highlight("%h[@" + name + "]hi"); r != exp {
+			t.Errorf("Expected %q but result was %q", exp, r)
+		}
+	}
+}
+
+var themeErrors = []struct {
+	theme string
+	line  int
+	err   string
+}{
+	{"a = bold\nb = fgRed+lold\n", 2, "%!h(BADATTR)"},
+	{"\n\na =\n", 3, "%!h(MISSING)"},
+	{"# comment\na = fgRed+\n", 2, "%!h(SHORT)"},
+	{"a = @b\n", 1, "%!h(BADATTR)"},
+	{"a = bold\nb = fgRed]junk%s\n", 2, "%!h(INVALID)"},
+	{"a = bold\nb bold\n", 2, ""},
+	{"a+b = bold\n", 1, ""},
+}
+
+func TestLoadThemeErrors(t *testing.T) {
+	t.Parallel()
+	for _, c := range themeErrors {
+		_, err := LoadTheme(strings.NewReader(c.theme))
+		terr, ok := err.(*ThemeError)
+		if !ok {
+			t.Errorf("Expected a *ThemeError from %q but result was %v", c.theme, err)
+			continue
+		}
+		if terr.Line != c.line {
+			t.Errorf("Expected line %d from %q but result was %d", c.line, c.theme, terr.Line)
+		}
+		if c.err == "" {
+			continue
+		}
+		if aerr, ok := terr.Err.(*AttrError); !ok || aerr.Err != c.err {
+			t.Errorf("Expected %q from %q but result was %v", c.err, c.theme, terr.Err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package color
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// escapeLen returns the length of the control sequence at the start of s,
+// or 0 if s does not start with one.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes followed by a final byte.
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		// OSC: terminated by BEL or ST.
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	// Intermediate bytes followed by a final byte, e.g. "\x1b(B".
+	i := 1
+	for i < len(s)-1 && s[i] >= 0x20 && s[i] <= 0x2f {
+		i++
+	}
+	return i + 1
+}
+
+// wideRanges are the ranges of East Asian Wide and Fullwidth characters and emoji
+// presentation characters, which occupy two columns.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x17000, 0x18cff}, {0x1b000, 0x1b2ff}, {0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f251}, {0x1f260, 0x1f265},
+	{0x1f300, 0x1f64f}, {0x1f680, 0x1f6ff}, {0x1f7e0, 0x1f7eb}, {0x1f90c, 0x1f9ff},
+	{0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of columns r occupies in a terminal.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || r >= 0x1160 && r <= 0x11ff:
+		// Combining marks, format characters like the zero width joiner
+		// and Hangul vowels and final consonants.
+		return 0
+	}
+	i := sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i][1] >= r
+	})
+	if i < len(wideRanges) && wideRanges[i][0] <= r {
+		return 2
+	}
+	return 1
+}
+
+// VisibleWidth returns the number of columns s occupies in a terminal.
+// Control sequences, such as those written for highlight verbs, occupy none,
+// while East Asian wide characters and emoji occupy two.
+func VisibleWidth(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if e := escapeLen(s[i:]); e > 0 {
+			i += e
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// Truncate returns s cut to at most width columns. If s is wider, its end is replaced
+// with tail, e.g. "…", so that the result including tail still fits. The tail has the
+// attributes active at the cut, and the resets after the cut are kept so that they are
+// still reset at the end. Attributes and hyperlinks s leaves active are reset after tail.
+func Truncate(s string, width int, tail string) string {
+	if VisibleWidth(s) <= width {
+		return s
+	}
+	if VisibleWidth(tail) > width {
+		tail = truncateVisible(tail, width, "")
+	}
+	return truncateVisible(s, width-VisibleWidth(tail), tail)
+}
+
+// truncateVisible returns the longest prefix of the visible characters of s that
+// occupies at most n columns, followed by tail if any were cut. The control sequences
+// after the cut are dropped, except for those that reset the attributes or close the
+// hyperlink active at the cut, so that they are still reset where s resets them.
+// If any were cut, the attributes still active at the end are reset.
+func truncateVisible(s string, n int, tail string) string {
+	var b bytes.Buffer
+	b.Grow(len(s) + len(tail))
+	var st attrState
+	cut := false
+	for i := 0; i < len(s); {
+		if e := escapeLen(s[i:]); e > 0 {
+			if seq := s[i : i+e]; !cut || isReset(seq) && st.active() {
+				b.WriteString(seq)
+				st.update(seq)
+			}
+			i += e
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if w := runeWidth(r); !cut && w <= n {
+			b.WriteString(s[i : i+size])
+			n -= w
+		} else if !cut {
+			b.WriteString(tail)
+			cut = true
+		}
+		i += size
+	}
+	if cut {
+		st.end(&b)
+	}
+	return b.String()
+}
+
+// PadRight returns s followed by as many spaces as needed for it to occupy width columns.
+func PadRight(s string, width int) string {
+	return pad(s, width, 0, 1)
+}
+
+// PadLeft returns s preceded by as many spaces as needed for it to occupy width columns.
+func PadLeft(s string, width int) string {
+	return pad(s, width, 1, 0)
+}
+
+// PadCenter returns s surrounded by as many spaces as needed for it to occupy width
+// columns. If the spaces cannot be split evenly, the extra one goes after s.
+func PadCenter(s string, width int) string {
+	return pad(s, width, 1, 1)
+}
+
+// pad pads s to width columns, splitting the spaces before and after s in the ratio
+// left:right.
+func pad(s string, width, left, right int) string {
+	n := width - VisibleWidth(s)
+	if n <= 0 {
+		return s
+	}
+	l := n * left / (left + right)
+	return strings.Repeat(" ", l) + s + strings.Repeat(" ", n-l)
+}
+
+// needsPadding reports whether fmt would pad s incorrectly because its width
+// in columns differs from its number of runes.
+func needsPadding(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' || s[i] >= utf8.RuneSelf {
+			return VisibleWidth(s) != utf8.RuneCountInString(s)
+		}
+	}
+	return false
+}
+
+// padded is a string that is padded and truncated by its width in columns when formatted.
+type padded string
+
+// Format implements fmt.Formatter.
+func (s padded) Format(st fmt.State, verb rune) {
+	formatString(st, verb, string(s))
+}
+
+// formatString formats s for verb. For the %s and %v verbs, s is truncated to the
+// precision and then padded to the width, both measured in columns.
+// Other verbs, like %q, format s as fmt would.
+func formatString(st fmt.State, verb rune, s string) {
+	if verb != 's' && verb != 'v' {
+		fmt.Fprintf(st, directive(st, verb), s)
+		return
+	}
+	if p, ok := st.Precision(); ok {
+		s = truncateVisible(s, p, "")
+	}
+	w, ok := st.Width()
+	if !ok || w <= VisibleWidth(s) {
+		io.WriteString(st, s)
+		return
+	}
+	pad := " "
+	if st.Flag('0') && !st.Flag('-') {
+		pad = "0"
+	}
+	pad = strings.Repeat(pad, w-VisibleWidth(s))
+	if st.Flag('-') {
+		io.WriteString(st, s+pad)
+	} else {
+		io.WriteString(st, pad+s)
+	}
+}
+
+// directive reconstructs the fmt directive for verb from the flags, width and precision in st.
+func directive(st fmt.State, verb rune) string {
+	b := []byte{'%'}
+	for _, c := range "+-# 0" {
+		if st.Flag(int(c)) {
+			b = append(b, byte(c))
+		}
+	}
+	if w, ok := st.Width(); ok {
+		b = strconv.AppendInt(b, int64(w), 10)
+	}
+	if p, ok := st.Precision(); ok {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(p), 10)
+	}
+	return string(append(b, string(verb)...))
+}
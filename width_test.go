@@ -0,0 +1,147 @@
+package color
+
+import "testing"
+
+var visibleWidths = map[string]int{
+	"":                                 0,
+	"foo":                              3,
+	"héllo":                            5,
+	"\x1b[31mfoo\x1b(B\x1b[m":          3,
+	"\x1b[38;2;1;2;3mhi":               2,
+	"\x1b]8;;http://x.y\x1b\\link":     4,
+	"\x1b]8;;http://x.y\alink\x1b]8;;": 4,
+	"\x1b[":                            0,
+	"日本語":                              6,
+	"a😀b":                              4,
+	"e\u0301":                          1,
+	"\x1b[1m한국\x1b(B\x1b[m":            4,
+	"ｆｕｌｌ":                             8,
+	"ﾊﾝ":                               2,
+}
+
+func TestVisibleWidth(t *testing.T) {
+	t.Parallel()
+	for k, v := range visibleWidths {
+		if r := VisibleWidth(k); r != v {
+			t.Errorf("Expected %d from %q but result was %d", v, k, r)
+		}
+	}
+}
+
+var truncations = []struct {
+	s   string
+	n   int
+	exp string
+}{
+	{"foobar", 3, "foo"},
+	{"foo", 5, "foo"},
+	{"héllo", 2, "hé"},
+	{"\x1b[31mfoo\x1b(B\x1b[m", 1, "\x1b[31mf\x1b(B\x1b[m"},
+	{"\x1b[1mfoo\x1b[31mbar\x1b(B\x1b[m", 4, "\x1b[1mfoo\x1b[31mb\x1b(B\x1b[m"},
+	{"\x1b[1mfoo", 0, "\x1b[1m\x1b[m"},
+	{"\x1b[1mfoo", 3, "\x1b[1mfoo"},
+	{"日本語", 4, "日本"},
+	{"日本語", 3, "日"},
+	{"a日b", 2, "a"},
+}
+
+func TestTruncateVisible(t *testing.T) {
+	t.Parallel()
+	for _, c := range truncations {
+		if r := truncateVisible(c.s, c.n, ""); r != c.exp {
+			t.Errorf("Expected %q from %q and %d but result was %q", c.exp, c.s, c.n, r)
+		}
+	}
+}
+
+var paddingNeeded = map[string]bool{
+	"foo":              false,
+	"héllo":            false,
+	"\x1b[1mfoo":       true,
+	"日本":               true,
+	"e\u0301":          true,
+	"\x1b[1mfoo\x1b[m": true,
+}
+
+func TestNeedsPadding(t *testing.T) {
+	t.Parallel()
+	for k, v := range paddingNeeded {
+		if r := needsPadding(k); r != v {
+			t.Errorf("Expected %t from %q but result was %t", v, k, r)
+		}
+	}
+}
+
+var truncationsTail = []struct {
+	s    string
+	n    int
+	tail string
+	exp  string
+}{
+	{"foobar", 6, "…", "foobar"},
+	{"foobar", 4, "…", "foo…"},
+	{"foobar", 4, "...", "f..."},
+	{"foobar", 2, "...", ".."},
+	{"\x1b[31mfoobar\x1b(B\x1b[m", 4, "…", "\x1b[31mfoo…\x1b(B\x1b[m"},
+	{"日本語", 5, "…", "日本…"},
+	{"日本語", 4, "…", "日…"},
+	{"ab\x1b[31mcd\x1b[m", 2, "…", "a…"},
+	{"\x1b]8;;u\x1b\\abcd\x1b]8;;\x1b\\", 2, "…", "\x1b]8;;u\x1b\\a…\x1b]8;;\x1b\\"},
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+	for _, c := range truncationsTail {
+		if r := Truncate(c.s, c.n, c.tail); r != c.exp {
+			t.Errorf("Expected %q from %q, %d and %q but result was %q", c.exp, c.s, c.n, c.tail, r)
+		}
+	}
+}
+
+func TestTruncateHighlighted(t *testing.T) {
+	t.Parallel()
+	term := BuiltinTerminal()
+	s := term.Run("%h[fgRed]error:%r %h[bold]connection refused%r", Ansi256)
+	exp := term.Run("%h[fgRed]error:%r %h[bold]con…%r", Ansi256)
+	if r := Truncate(s, 11, "…"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	exp = term.Run("%h[fgRed]err…%r", Ansi256)
+	if r := Truncate(s, 4, "…"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	s = term.Run("%h[bold]connection refused", Ansi256)
+	exp = term.Run("%h[bold]conn…", Ansi256) + "\x1b[m"
+	if r := Truncate(s, 5, "…"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+	s = term.Run("%l[http://x]%h[bold]connection refused", Ansi256)
+	exp = term.Run("%l[http://x]%h[bold]conn…", Ansi256) + "\x1b]8;;\x1b\\\x1b[m"
+	if r := Truncate(s, 5, "…"); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+var pads = []struct {
+	f     func(string, int) string
+	s     string
+	width int
+	exp   string
+}{
+	{PadRight, "ab", 5, "ab   "},
+	{PadLeft, "ab", 5, "   ab"},
+	{PadCenter, "ab", 5, " ab  "},
+	{PadCenter, "ab", 6, "  ab  "},
+	{PadRight, "abcdef", 5, "abcdef"},
+	{PadRight, "日本", 5, "日本 "},
+	{PadLeft, "\x1b[1mab\x1b[m", 4, "  \x1b[1mab\x1b[m"},
+}
+
+func TestPad(t *testing.T) {
+	t.Parallel()
+	for _, c := range pads {
+		if r := c.f(c.s, c.width); r != c.exp {
+			t.Errorf("Expected %q from %q and %d but result was %q", c.exp, c.s, c.width, r)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package color
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// attrState tracks the attributes set by the control sequences in a string.
+type attrState struct {
+	sgr  []string // SGR sequences written since the last reset
+	link string   // sequence that opened the current hyperlink
+}
+
+// update updates the state with the control sequence seq.
+func (st *attrState) update(seq string) {
+	switch {
+	case strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m"):
+		params := seq[2 : len(seq)-1]
+		if params == "" || params == "0" {
+			st.sgr = st.sgr[:0]
+			return
+		}
+		if strings.HasPrefix(params, "0;") {
+			st.sgr = st.sgr[:0]
+		}
+		st.sgr = append(st.sgr, seq)
+	case strings.HasPrefix(seq, "\x1b]8;"):
+		if isLinkEnd(seq) {
+			st.link = ""
+		} else {
+			st.link = seq
+		}
+	}
+}
+
+// isReset reports whether the control sequence seq only resets attributes,
+// closes a hyperlink or has no effect on the attributes.
+func isReset(seq string) bool {
+	switch {
+	case strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m"):
+		params := seq[2 : len(seq)-1]
+		return params == "" || params == "0"
+	case strings.HasPrefix(seq, "\x1b]8;"):
+		return isLinkEnd(seq)
+	}
+	// Character set designations, like the "\x1b(B" in xterm's sgr0.
+	return len(seq) == 3 && seq[1] >= 0x28 && seq[1] <= 0x2b
+}
+
+// isLinkEnd reports whether the OSC 8 sequence seq closes a hyperlink.
+func isLinkEnd(seq string) bool {
+	seq = strings.TrimSuffix(strings.TrimSuffix(seq, "\x1b\\"), "\a")
+	return strings.HasSuffix(seq, ";")
+}
+
+// end writes the control sequences that reset the attributes of st.
+func (st *attrState) end(b *bytes.Buffer) {
+	if st.link != "" {
+		b.WriteString("\x1b]8;;\x1b\\")
+	}
+	if len(st.sgr) > 0 {
+		b.WriteString("\x1b[m")
+	}
+}
+
+// restore writes the control sequences that set the attributes of st again.
+func (st *attrState) restore(b *bytes.Buffer) {
+	b.WriteString(st.link)
+	for _, seq := range st.sgr {
+		b.WriteString(seq)
+	}
+}
+
+// active reports whether any attributes or a hyperlink are active.
+func (st *attrState) active() bool {
+	return len(st.sgr) > 0 || st.link != ""
+}
+
+// ActiveAttributes reports whether s ends with attributes set by its SGR sequences and
+// whether it ends inside a hyperlink, i.e. whether s has to be followed by %r or %L so
+// that what is written after it is not affected.
+func ActiveAttributes(s string) (sgr, link bool) {
+	var st attrState
+	for i := strings.IndexByte(s, '\x1b'); i >= 0; i = strings.IndexByte(s, '\x1b') {
+		e := escapeLen(s[i:])
+		if e == 0 {
+			e = 1
+		}
+		st.update(s[i : i+e])
+		s = s[i+e:]
+	}
+	return len(st.sgr) > 0, st.link != ""
+}
+
+// Wrap wraps s into lines of at most width columns. Lines are broken at spaces, and
+// words wider than width are broken wherever they must be. The attributes and hyperlink
+// active at each line break, including those already in s, are reset before the break
+// and set again after it, so each line can be printed on its own, e.g. in a table cell.
+// s is returned unchanged if width is not positive.
+func Wrap(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	w := wrapper{width: width}
+	for len(s) > 0 {
+		switch s[0] {
+		case '\n':
+			w.newline()
+			s = s[1:]
+			continue
+		case ' ':
+			i := strings.IndexFunc(s, func(r rune) bool { return r != ' ' })
+			if i < 0 {
+				i = len(s)
+			}
+			w.spaces, s = s[:i], s[i:]
+			continue
+		}
+		i := strings.IndexAny(s, " \n")
+		if i < 0 {
+			i = len(s)
+		}
+		w.word(s[:i])
+		s = s[i:]
+	}
+	if w.lw+len(w.spaces) <= width {
+		w.b.WriteString(w.spaces)
+	}
+	return w.b.String()
+}
+
+// wrapper holds the state of Wrap.
+type wrapper struct {
+	b      bytes.Buffer
+	st     attrState
+	width  int    // maximum width of a line
+	lw     int    // width of the current line
+	spaces string // spaces between the current line and the next word
+}
+
+// newline breaks the line.
+func (w *wrapper) newline() {
+	w.st.end(&w.b)
+	w.b.WriteByte('\n')
+	w.st.restore(&w.b)
+	w.lw, w.spaces = 0, ""
+}
+
+// word writes the word s, including its control sequences, after the pending spaces
+// or on a new line if it does not fit. If s does not fit on a line of its own either,
+// it is broken.
+func (w *wrapper) word(s string) {
+	sw := VisibleWidth(s)
+	if w.lw > 0 && w.lw+len(w.spaces)+sw > w.width {
+		w.newline()
+	} else {
+		w.b.WriteString(w.spaces)
+		w.lw += len(w.spaces)
+	}
+	w.spaces = ""
+	for i := 0; i < len(s); {
+		if e := escapeLen(s[i:]); e > 0 {
+			w.st.update(s[i : i+e])
+			w.b.WriteString(s[i : i+e])
+			i += e
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := runeWidth(r)
+		if w.lw > 0 && w.lw+rw > w.width {
+			w.newline()
+		}
+		w.b.WriteString(s[i : i+size])
+		w.lw += rw
+		i += size
+	}
+}
@@ -0,0 +1,139 @@
+package color
+
+import (
+	"strings"
+	"testing"
+)
+
+var wraps = []struct {
+	s     string
+	width int
+	exp   string
+}{
+	{"the quick brown fox", 10, "the quick\nbrown fox"},
+	{"the quick brown fox", 0, "the quick brown fox"},
+	{"abcdefghij", 4, "abcd\nefgh\nij"},
+	{"ab abcdefghij", 4, "ab\nabcd\nefgh\nij"},
+	{"a  b", 10, "a  b"},
+	{"a    b", 3, "a\nb"},
+	{"ab\ncd ef", 5, "ab\ncd ef"},
+	{"日本語です", 4, "日本\n語で\nす"},
+	{"\x1b[1mfoo bar\x1b[m", 3, "\x1b[1mfoo\x1b[m\n\x1b[1mbar\x1b[m"},
+	{"\x1b[1mfoo\x1b[m bar", 3, "\x1b[1mfoo\x1b[m\nbar"},
+	{"\x1b[1m\x1b[31mab\x1b[0;4mcd\x1b[m", 2, "\x1b[1m\x1b[31mab\x1b[0;4m\x1b[m\n\x1b[0;4mcd\x1b[m"},
+	{"x ab\x1b[1mcd\x1b[m", 4, "x\nab\x1b[1mcd\x1b[m"},
+	{"\x1b]8;;u\x1b\\ab cd\x1b]8;;\x1b\\", 2, "\x1b]8;;u\x1b\\ab\x1b]8;;\x1b\\\n\x1b]8;;u\x1b\\cd\x1b]8;;\x1b\\"},
+}
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+	for _, c := range wraps {
+		if r := Wrap(c.s, c.width); r != c.exp {
+			t.Errorf("Expected %q from %q and %d but result was %q", c.exp, c.s, c.width, r)
+		}
+	}
+}
+
+// stripEscapes removes all control sequences from s.
+func stripEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if e := escapeLen(s[i:]); e > 0 {
+			i += e
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+func TestWrapHighlighted(t *testing.T) {
+	t.Parallel()
+	term := BuiltinTerminal()
+	const f = "%h[fgRed+bold]the quick %h[underline]brown%r fox %l[http://x.y]jumps over%L the lazy dog"
+	s := term.Run(f, Ansi256)
+	for width := 1; width <= 20; width++ {
+		r := Wrap(s, width)
+		for _, line := range strings.Split(r, "\n") {
+			if w := VisibleWidth(line); w > width {
+				t.Errorf("Expected %q to be at most %d columns wide but it was %d", line, width, w)
+			}
+			var st attrState
+			for i := 0; i < len(line); {
+				if e := escapeLen(line[i:]); e > 0 {
+					st.update(line[i : i+e])
+					i += e
+					continue
+				}
+				i++
+			}
+			if len(st.sgr) > 0 || st.link != "" {
+				t.Errorf("Expected the attributes to be reset at the end of %q", line)
+			}
+		}
+		if exp := Wrap(Strip(f), width); stripEscapes(r) != exp {
+			t.Errorf("Expected %q but result was %q", exp, stripEscapes(r))
+		}
+	}
+	exp := term.Run("%h[fgRed+bold]the%r\n%h[fgRed+bold]quick%r", Ansi256)
+	exp = strings.Replace(exp, "\x1b(B\x1b[m\n", "\x1b[m\n", 1)
+	if r := Wrap(term.Run("%h[fgRed+bold]the quick%r", Ansi256), 5); r != exp {
+		t.Errorf("Expected %q but result was %q", exp, r)
+	}
+}
+
+func TestAttrState(t *testing.T) {
+	t.Parallel()
+	term := BuiltinTerminal()
+	var st attrState
+	st.update(term.Run("%h[fgRed]", Ansi256))
+	st.update("\x1b[1m")
+	if len(st.sgr) != 2 {
+		t.Errorf("Expected 2 active SGR sequences but result was %q", st.sgr)
+	}
+	for _, seq := range []string{"\x1b(B", "\x1b[m"} {
+		if !isReset(seq) {
+			t.Errorf("Expected %q to be a reset", seq)
+		}
+		st.update(seq)
+	}
+	if len(st.sgr) != 0 {
+		t.Errorf("Expected no active SGR sequences but result was %q", st.sgr)
+	}
+	link := term.Run("%l[http://x.y]", Ansi256)
+	st.update(link)
+	if st.link != link {
+		t.Errorf("Expected the link %q but result was %q", link, st.link)
+	}
+	end := term.Run("%L", Ansi256)
+	if !isReset(end) {
+		t.Errorf("Expected %q to be a reset", end)
+	}
+	st.update(end)
+	if st.link != "" {
+		t.Errorf("Expected no link but result was %q", st.link)
+	}
+}
+
+var activeAttributes = []struct {
+	s         string
+	sgr, link bool
+}{
+	{"", false, false},
+	{"foo", false, false},
+	{"\x1b[31mfoo", true, false},
+	{"\x1b[31mfoo\x1b(B\x1b[m", false, false},
+	{"\x1b]8;;u\x1b\\foo", false, true},
+	{"\x1b]8;;u\x1b\\\x1b[1mfoo\x1b]8;;\x1b\\", true, false},
+	{"\x1b", false, false},
+}
+
+func TestActiveAttributes(t *testing.T) {
+	t.Parallel()
+	for _, c := range activeAttributes {
+		if sgr, link := ActiveAttributes(c.s); sgr != c.sgr || link != c.link {
+			t.Errorf("Expected %v and %v from %q but result was %v and %v", c.sgr, c.link, c.s, sgr, link)
+		}
+	}
+}